@@ -8,29 +8,51 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 
 	"github.com/docker/libtrust"
 	"github.com/docker/licensing/lib/errors"
 	"github.com/docker/licensing/lib/go-auth/jwt"
 	"github.com/docker/licensing/lib/go-clientlib"
+	validation "github.com/docker/licensing/lib/go-validation"
 	"github.com/docker/licensing/model"
+	"github.com/docker/licensing/types"
 )
 
 const (
 	trialProductID  = "docker-ee-trial"
 	trialRatePlanID = "free-trial"
+
+	// dockerProductPrefix is shared by the ids of all docker enterprise products
+	dockerProductPrefix = "docker"
+
+	// defaultConcurrency is the default maximum number of concurrent requests made by fan out calls
+	defaultConcurrency = 4
 )
 
 // Client represents the licensing package interface, including methods for authentication and interaction with Docker
 // licensing, accounts, and billing services
 type Client interface {
 	LoginViaAuth(ctx context.Context, username, password string) (authToken string, err error)
+	LoginWith2FA(ctx context.Context, login2FAToken, code string) (authToken string, err error)
+	Login(ctx context.Context, creds Credentials) (authToken string, err error)
 	GetHubUserOrgs(ctx context.Context, authToken string) (orgs []model.Org, err error)
+	AllHubUserOrgs(ctx context.Context, authToken string, pageSize int) (orgs []model.Org, err error)
+	IterateHubUserOrgs(ctx context.Context, authToken string, pageSize int) *OrgIterator
 	GetHubUserByName(ctx context.Context, username string) (user *model.User, err error)
 	VerifyLicense(ctx context.Context, license model.IssuedLicense) (res *model.CheckResponse, err error)
 	GenerateNewTrialSubscription(ctx context.Context, authToken, dockerID string) (subscriptionID string, err error)
+	CheckTrialEligibility(ctx context.Context, authToken, dockerID string) (eligibility *model.TrialEligibility, err error)
+	CreateSubscription(ctx context.Context, authToken string, request *model.SubscriptionCreationRequest) (subscription *model.SubscriptionDetail, err error)
 	ListSubscriptions(ctx context.Context, authToken, dockerID string) (response []*model.Subscription, err error)
 	ListSubscriptionsDetails(ctx context.Context, authToken, dockerID string) (response []*model.SubscriptionDetail, err error)
+	QuerySubscriptions(ctx context.Context, authToken string, query *model.SubscriptionQuery) (response []*model.SubscriptionDetail, err error)
+	IterateSubscriptions(ctx context.Context, authToken string, query *model.SubscriptionQuery) *SubscriptionIterator
+	ListAccessibleSubscriptions(ctx context.Context, authToken, dockerID string) (response *model.AccessibleSubscriptions, err error)
+	GetSubscription(ctx context.Context, authToken, subscriptionID string) (subscription *model.SubscriptionDetail, err error)
+	CancelSubscription(ctx context.Context, authToken, subscriptionID string, request *model.SubscriptionCancellationRequest) (subscription *model.SubscriptionDetail, err error)
+	UpdatePricingComponents(ctx context.Context, authToken, subscriptionID string, request *model.SubscriptionPricingComponentsUpdateRequest) (subscription *model.SubscriptionDetail, err error)
+	RenewSubscription(ctx context.Context, authToken, subscriptionID string, request *model.SubscriptionRenewalRequest) (subscription *model.SubscriptionDetail, err error)
 	DownloadLicenseFromHub(ctx context.Context, authToken, subscriptionID string) (license *model.IssuedLicense, err error)
 	ParseLicense(license []byte) (parsedLicense *model.IssuedLicense, err error)
 	StoreLicense(ctx context.Context, dclnt WrappedDockerClient, licenses *model.IssuedLicense, localRootDir string) error
@@ -49,10 +71,36 @@ func (c *client) LoginViaAuth(ctx context.Context, username, password string) (s
 	return creds.Token, nil
 }
 
+// LoginWith2FA completes the login of an account requiring two factor authentication, submitting the code along
+// with the token of the rejected login, see model.LoginError.TwoFactorRequired
+func (c *client) LoginWith2FA(ctx context.Context, login2FAToken, code string) (string, error) {
+	creds, err := c.login2FA(ctx, login2FAToken, code)
+	if err != nil {
+		return "", err
+	}
+
+	return creds.Token, nil
+}
+
+// Login exchanges the given credentials for a hub JWT, see jwt.NewContext
+func (c *client) Login(ctx context.Context, creds Credentials) (string, error) {
+	token, err := creds.Login(ctx, c)
+	if err != nil {
+		return "", errors.WithMessage(err, "Failed to login")
+	}
+
+	return token, nil
+}
+
+// GetHubUserOrgs returns all orgs of the authenticated user, following pagination
 func (c *client) GetHubUserOrgs(ctx context.Context, authToken string) ([]model.Org, error) {
-	ctx = jwt.NewContext(ctx, authToken)
+	return c.AllHubUserOrgs(ctx, authToken, 0)
+}
 
-	orgs, err := c.getUserOrgs(ctx, model.PaginationParams{})
+// AllHubUserOrgs returns all orgs of the authenticated user, fetching pageSize orgs per request. A pageSize of
+// zero uses the hub default.
+func (c *client) AllHubUserOrgs(ctx context.Context, authToken string, pageSize int) ([]model.Org, error) {
+	orgs, err := c.IterateHubUserOrgs(ctx, authToken, pageSize).All()
 	if err != nil {
 		return nil, errors.WithMessage(err, "Failed to get orgs for user")
 	}
@@ -60,6 +108,14 @@ func (c *client) GetHubUserOrgs(ctx context.Context, authToken string) ([]model.
 	return orgs, nil
 }
 
+// IterateHubUserOrgs returns an iterator over the orgs of the authenticated user, fetching pageSize orgs per
+// request. A pageSize of zero uses the hub default.
+func (c *client) IterateHubUserOrgs(ctx context.Context, authToken string, pageSize int) *OrgIterator {
+	ctx = jwt.NewContext(ctx, authToken)
+
+	return c.userOrgs(ctx, model.PaginationParams{PageSize: pageSize})
+}
+
 func (c *client) GetHubUserByName(ctx context.Context, username string) (*model.User, error) {
 	user, err := c.getUserByName(ctx, username)
 	if err != nil {
@@ -80,10 +136,14 @@ func (c *client) VerifyLicense(ctx context.Context, license model.IssuedLicense)
 	return res, nil
 }
 
+// GenerateNewTrialSubscription creates a free trial subscription for the given dockerID, returning its id. If the
+// billing service rejects the trial because the user already had one, the returned error matches ErrTrialIneligible.
 func (c *client) GenerateNewTrialSubscription(ctx context.Context, authToken, dockerID string) (string, error) {
-	ctx = jwt.NewContext(ctx, authToken)
+	fields := errors.Fields{
+		"docker_id": dockerID,
+	}
 
-	sub, err := c.createSubscription(ctx, &model.SubscriptionCreationRequest{
+	request := &model.SubscriptionCreationRequest{
 		Name:            "Docker Enterprise Free Trial",
 		DockerID:        dockerID,
 		ProductID:       trialProductID,
@@ -91,35 +151,121 @@ func (c *client) GenerateNewTrialSubscription(ctx context.Context, authToken, do
 		Eusa: &model.EusaState{
 			Accepted: true,
 		},
-	})
+	}
+	if err := validate(request); err != nil {
+		return "", errors.Wrap(err, fields)
+	}
+
+	ctx = jwt.NewContext(ctx, authToken)
+
+	sub, err := c.createSubscription(ctx, request, conflictErrorOpt(ErrTrialIneligible))
 	if err != nil {
-		return "", errors.Wrap(err, errors.Fields{
+		return "", errors.Wrap(err, fields)
+	}
+
+	return sub.ID, nil
+}
+
+// CheckTrialEligibility reports whether the given dockerID may start a free trial. Users are not eligible if they
+// have ever had a trial subscription, including expired or cancelled ones, or if they already have a live
+// subscription to a docker enterprise product.
+func (c *client) CheckTrialEligibility(ctx context.Context, authToken, dockerID string) (*model.TrialEligibility, error) {
+	ctx = jwt.NewContext(ctx, authToken)
+
+	subs, err := c.listSubscriptions(ctx, &model.SubscriptionQuery{DockerID: dockerID})
+	if err != nil {
+		return nil, errors.Wrap(err, errors.Fields{
 			"docker_id": dockerID,
 		})
 	}
 
-	return sub.ID, nil
+	return trialEligibility(dockerID, subs), nil
 }
 
-// ListSubscriptions returns basic descriptions of all subscriptions to docker enterprise products for the given dockerID
+func trialEligibility(dockerID string, subs []*model.Subscription) *model.TrialEligibility {
+	var live *model.Subscription
+	for _, sub := range subs {
+		// org subscriptions do not count against the user
+		if sub.DockerID != "" && sub.DockerID != dockerID {
+			continue
+		}
+
+		if sub.ProductRatePlan == trialRatePlanID || sub.ProductID == trialProductID {
+			return &model.TrialEligibility{
+				Reason:         model.TrialAlreadyUsed,
+				SubscriptionID: sub.ID,
+			}
+		}
+
+		if live == nil && strings.HasPrefix(sub.ProductID, dockerProductPrefix) {
+			switch types.State(sub.State) {
+			case types.Active, types.Preparing:
+				live = sub
+			}
+		}
+	}
+
+	if live != nil {
+		return &model.TrialEligibility{
+			Reason:         model.TrialHasSubscription,
+			SubscriptionID: live.ID,
+		}
+	}
+
+	return &model.TrialEligibility{
+		Eligible: true,
+	}
+}
+
+// CreateSubscription creates a subscription as described by request, returning its details. The request is
+// validated before it is sent; if invalid, the returned error wraps the validation.Errors found.
+func (c *client) CreateSubscription(ctx context.Context, authToken string, request *model.SubscriptionCreationRequest) (*model.SubscriptionDetail, error) {
+	if request == nil {
+		request = &model.SubscriptionCreationRequest{}
+	}
+
+	fields := errors.Fields{
+		"docker_id":         request.DockerID,
+		"product_id":        request.ProductID,
+		"product_rate_plan": request.ProductRatePlan,
+	}
+	if err := validate(request); err != nil {
+		return nil, errors.Wrap(err, fields)
+	}
+
+	ctx = jwt.NewContext(ctx, authToken)
+
+	sub, err := c.createSubscription(ctx, request)
+	if err != nil {
+		return nil, errors.Wrap(err, fields)
+	}
+
+	return sub, nil
+}
+
+// ListSubscriptions returns basic descriptions of the subscriptions to docker enterprise products for the given
+// dockerID. Only the first page of the billing service, of its default size, is listed, and the product filter
+// is applied to that page, see model.SubscriptionQuery.
 func (c *client) ListSubscriptions(ctx context.Context, authToken, dockerID string) ([]*model.Subscription, error) {
 	ctx = jwt.NewContext(ctx, authToken)
 
-	subs, err := c.listSubscriptions(ctx, map[string]string{"docker_id": dockerID})
+	query := &model.SubscriptionQuery{
+		DockerID:        dockerID,
+		IncludeOrgs:     true,
+		ProductIDPrefix: dockerProductPrefix,
+	}
+	subs, err := c.listSubscriptions(ctx, query)
 	if err != nil {
 		return nil, errors.Wrap(err, errors.Fields{
 			"docker_id": dockerID,
 		})
 	}
 
-	// filter out non docker licenses
 	dockerSubs := []*model.Subscription{}
 	for _, sub := range subs {
-		if !strings.HasPrefix(sub.ProductID, "docker") {
-			continue
+		if query.Matches(sub) {
+			dockerSubs = append(dockerSubs, sub)
 		}
-
-		dockerSubs = append(dockerSubs, sub)
 	}
 
 	return dockerSubs, nil
@@ -127,29 +273,242 @@ func (c *client) ListSubscriptions(ctx context.Context, authToken, dockerID stri
 
 // ListDetailedSubscriptions returns detailed subscriptions to docker enterprise products for the given dockerID
 func (c *client) ListSubscriptionsDetails(ctx context.Context, authToken, dockerID string) ([]*model.SubscriptionDetail, error) {
+	return c.QuerySubscriptions(ctx, authToken, &model.SubscriptionQuery{
+		DockerID:        dockerID,
+		ProductIDPrefix: dockerProductPrefix,
+	})
+}
+
+// QuerySubscriptions returns a single page of detailed subscriptions matching query, sorted by its Sort. Filters
+// the billing service does not support, and the sort, are applied to the page it returns, so the page may hold
+// fewer matching subscriptions than its size, see model.SubscriptionQuery. Use IterateSubscriptions to go
+// through all matching subscriptions.
+func (c *client) QuerySubscriptions(ctx context.Context, authToken string, query *model.SubscriptionQuery) ([]*model.SubscriptionDetail, error) {
+	if query == nil {
+		query = &model.SubscriptionQuery{}
+	}
+
+	fields := errors.Fields{
+		"docker_id": query.DockerID,
+	}
+	if err := validate(query); err != nil {
+		return nil, errors.Wrap(err, fields)
+	}
+
 	ctx = jwt.NewContext(ctx, authToken)
 
-	subs, err := c.listSubscriptionsDetails(ctx, map[string]string{"docker_id": dockerID})
+	subs, err := c.listSubscriptionsDetails(ctx, query)
+	if err != nil {
+		return nil, errors.Wrap(err, fields)
+	}
+
+	matching := []*model.SubscriptionDetail{}
+	for _, sub := range subs {
+		if query.Matches(&sub.Subscription) {
+			matching = append(matching, sub)
+		}
+	}
+	query.SortSubscriptions(matching)
+
+	return matching, nil
+}
+
+// IterateSubscriptions returns an iterator over all detailed subscriptions matching query, starting at its page
+// (or the first page) and fetching further pages as needed. Subscriptions are iterated in the billing service
+// order: the query may not set a Sort, sort the iterated subscriptions with SubscriptionQuery.SortSubscriptions
+// instead.
+func (c *client) IterateSubscriptions(ctx context.Context, authToken string, query *model.SubscriptionQuery) *SubscriptionIterator {
+	it := &SubscriptionIterator{
+		ctx:  jwt.NewContext(ctx, authToken),
+		clnt: c,
+	}
+	if query != nil {
+		it.query = *query
+	}
+	if it.query.Page == 0 {
+		it.query.Page = 1
+	}
+	if it.query.PageSize == 0 {
+		it.query.PageSize = defaultIteratorPageSize
+	}
+
+	if err := validate(&it.query); err != nil {
+		it.err = errors.Wrap(err, errors.Fields{
+			"docker_id": it.query.DockerID,
+		})
+	} else if it.query.Sort != "" {
+		it.err = errors.Wrapf(validation.Errors{validation.InvalidValue("sort", it.query.Sort)}, errors.Fields{
+			"docker_id": it.query.DockerID,
+		}, "subscriptions cannot be sorted across pages")
+	}
+
+	return it
+}
+
+// ListAccessibleSubscriptions returns the docker enterprise subscriptions of the given dockerID and of every org
+// the user belongs to, grouped by owner. Owners are listed concurrently, up to the client's concurrency. Failures
+// to list an owner's subscriptions are reported on that owner rather than failing the call; an error is only
+// returned if the user's orgs could not be listed.
+func (c *client) ListAccessibleSubscriptions(ctx context.Context, authToken, dockerID string) (*model.AccessibleSubscriptions, error) {
+	orgs, err := c.GetHubUserOrgs(ctx, authToken)
 	if err != nil {
 		return nil, errors.Wrap(err, errors.Fields{
 			"docker_id": dockerID,
 		})
 	}
 
-	// filter out non docker licenses
-	dockerSubs := []*model.SubscriptionDetail{}
-	for _, sub := range subs {
-		if !strings.HasPrefix(sub.ProductID, "docker") {
-			continue
+	owners := make([]*model.OwnerSubscriptions, 0, len(orgs)+1)
+	owners = append(owners, &model.OwnerSubscriptions{DockerID: dockerID})
+	for _, org := range orgs {
+		owners = append(owners, &model.OwnerSubscriptions{DockerID: org.ID, Orgname: org.Orgname})
+	}
+
+	sem := make(chan struct{}, c.concurrency)
+	var wg sync.WaitGroup
+	for _, owner := range owners {
+		wg.Add(1)
+		go func(owner *model.OwnerSubscriptions) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			owner.Subscriptions, owner.Err = c.listOwnerSubscriptions(ctx, authToken, owner)
+			if owner.Err != nil {
+				owner.Error = owner.Err.Error()
+			}
+		}(owner)
+	}
+	wg.Wait()
+
+	return &model.AccessibleSubscriptions{Owners: owners}, nil
+}
+
+// listOwnerSubscriptions lists the docker enterprise subscriptions of owner, looking up the docker id of orgs
+// that were listed without one
+func (c *client) listOwnerSubscriptions(ctx context.Context, authToken string, owner *model.OwnerSubscriptions) ([]*model.SubscriptionDetail, error) {
+	fields := errors.Fields{
+		"docker_id": owner.DockerID,
+		"orgname":   owner.Orgname,
+	}
+
+	if owner.DockerID == "" {
+		org, err := c.getUserByName(ctx, owner.Orgname)
+		if err != nil {
+			return nil, errors.Wrap(err, fields)
 		}
+		owner.DockerID = org.ID
+		fields["docker_id"] = org.ID
+	}
 
-		dockerSubs = append(dockerSubs, sub)
+	subs, err := c.QuerySubscriptions(ctx, authToken, &model.SubscriptionQuery{
+		DockerID:        owner.DockerID,
+		ProductIDPrefix: dockerProductPrefix,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, fields)
 	}
 
-	return dockerSubs, nil
+	return subs, nil
+}
+
+// GetSubscription returns the details of the given subscription
+func (c *client) GetSubscription(ctx context.Context, authToken, subscriptionID string) (*model.SubscriptionDetail, error) {
+	fields := errors.Fields{
+		"subscriptionID": subscriptionID,
+	}
+	if err := validate(subscriptionIDParam(subscriptionID)); err != nil {
+		return nil, errors.Wrap(err, fields)
+	}
+
+	ctx = jwt.NewContext(ctx, authToken)
+
+	sub, err := c.getSubscription(ctx, subscriptionID)
+	if err != nil {
+		return nil, errors.Wrap(err, fields)
+	}
+
+	return sub, nil
+}
+
+// CancelSubscription cancels the given subscription, returning its updated details. A nil request cancels
+// the subscription at the end of its current period.
+func (c *client) CancelSubscription(ctx context.Context, authToken, subscriptionID string, request *model.SubscriptionCancellationRequest) (*model.SubscriptionDetail, error) {
+	if request == nil {
+		request = &model.SubscriptionCancellationRequest{}
+	}
+
+	fields := errors.Fields{
+		"subscriptionID": subscriptionID,
+	}
+	if err := validate(subscriptionIDParam(subscriptionID), request); err != nil {
+		return nil, errors.Wrap(err, fields)
+	}
+
+	ctx = jwt.NewContext(ctx, authToken)
+
+	sub, err := c.cancelSubscription(ctx, subscriptionID, request)
+	if err != nil {
+		return nil, errors.Wrap(err, fields)
+	}
+
+	return sub, nil
+}
+
+// UpdatePricingComponents changes the pricing component values of the given subscription, returning its
+// updated details
+func (c *client) UpdatePricingComponents(ctx context.Context, authToken, subscriptionID string, request *model.SubscriptionPricingComponentsUpdateRequest) (*model.SubscriptionDetail, error) {
+	if request == nil {
+		request = &model.SubscriptionPricingComponentsUpdateRequest{}
+	}
+
+	fields := errors.Fields{
+		"subscriptionID": subscriptionID,
+	}
+	if err := validate(subscriptionIDParam(subscriptionID), request); err != nil {
+		return nil, errors.Wrap(err, fields)
+	}
+
+	ctx = jwt.NewContext(ctx, authToken)
+
+	sub, err := c.updatePricingComponents(ctx, subscriptionID, request)
+	if err != nil {
+		return nil, errors.Wrap(err, fields)
+	}
+
+	return sub, nil
+}
+
+// RenewSubscription renews the given subscription for another period, returning its updated details. A nil
+// request renews the subscription with its current pricing components.
+func (c *client) RenewSubscription(ctx context.Context, authToken, subscriptionID string, request *model.SubscriptionRenewalRequest) (*model.SubscriptionDetail, error) {
+	if request == nil {
+		request = &model.SubscriptionRenewalRequest{}
+	}
+
+	fields := errors.Fields{
+		"subscriptionID": subscriptionID,
+	}
+	if err := validate(subscriptionIDParam(subscriptionID), request); err != nil {
+		return nil, errors.Wrap(err, fields)
+	}
+
+	ctx = jwt.NewContext(ctx, authToken)
+
+	sub, err := c.renewSubscription(ctx, subscriptionID, request)
+	if err != nil {
+		return nil, errors.Wrap(err, fields)
+	}
+
+	return sub, nil
 }
 
 func (c *client) DownloadLicenseFromHub(ctx context.Context, authToken, subscriptionID string) (*model.IssuedLicense, error) {
+	if err := validate(subscriptionIDParam(subscriptionID)); err != nil {
+		return nil, errors.Wrap(err, errors.Fields{
+			"subscriptionID": subscriptionID,
+		})
+	}
+
 	ctx = jwt.NewContext(ctx, authToken)
 
 	license, err := c.getLicenseFile(ctx, subscriptionID)
@@ -176,9 +535,11 @@ func (c *client) ParseLicense(license []byte) (*model.IssuedLicense, error) {
 }
 
 type client struct {
-	publicKeys []libtrust.PublicKey
-	hclient    *http.Client
-	baseURI    url.URL
+	publicKeys  []libtrust.PublicKey
+	hclient     *http.Client
+	baseURI     url.URL
+	concurrency int
+	tokenSource TokenSource
 }
 
 // Config holds licensing client configuration
@@ -187,19 +548,11 @@ type Config struct {
 	HTTPClient *http.Client
 	// used by licensing client to validate an issued license
 	PublicKeys []string
-}
-
-func errorSummary(body []byte) string {
-	var be struct {
-		Message string `json:"message"`
-	}
-
-	jsonErr := json.Unmarshal(body, &be)
-	if jsonErr != nil {
-		return clientlib.DefaultErrorSummary(body)
-	}
-
-	return be.Message
+	// maximum number of concurrent requests made by calls fanning out across accounts, defaults to 4
+	Concurrency int
+	// supplies the auth token of methods called with an empty authToken. Methods called with a token keep
+	// using it as is.
+	TokenSource TokenSource
 }
 
 // New creates a new licensing Client
@@ -214,10 +567,17 @@ func New(config *Config) (Client, error) {
 		hclient = &http.Client{}
 	}
 
+	concurrency := config.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+
 	return &client{
-		baseURI:    config.BaseURI,
-		hclient:    hclient,
-		publicKeys: publicKeys,
+		baseURI:     config.BaseURI,
+		hclient:     hclient,
+		publicKeys:  publicKeys,
+		concurrency: concurrency,
+		tokenSource: config.TokenSource,
 	}, nil
 }
 
@@ -239,38 +599,99 @@ func unmarshalPublicKey(publicKey string) (libtrust.PublicKey, error) {
 	pemBytes, err := base64.StdEncoding.DecodeString(publicKey)
 	if err != nil {
 		return nil, errors.Wrapf(err, errors.Fields{
-			"public_key": publicKey,
+			"public_key": errors.Redact(publicKey),
 		}, "decode public key failed")
 	}
 
 	key, err := libtrust.UnmarshalPublicKeyPEM(pemBytes)
 	if err != nil {
 		return nil, errors.Wrapf(err, errors.Fields{
-			"public_key": publicKey,
+			"public_key": errors.Redact(publicKey),
 		}, "unmarshal public key failed")
 	}
 	return key, nil
 }
 
+// doReq makes an authenticated request, sending the token carried by ctx. If ctx carries no token, one is obtained
+// from the client's TokenSource, and the request is retried once with a fresh token if it is rejected as
+// unauthorized. Without either, the request fails without being sent.
 func (c *client) doReq(ctx context.Context, method string, url *url.URL, opts ...clientlib.RequestOption) (*http.Request, *http.Response, error) {
-	return clientlib.Do(ctx, method, url.String(), append(c.requestDefaults(), opts...)...)
+	if tok, _ := jwt.FromContext(ctx); tok != "" {
+		return c.doWithToken(ctx, tok, method, url, opts...)
+	}
+
+	if c.tokenSource == nil {
+		return nil, nil, errors.Wrapf(ErrMissingAuthToken, errors.Fields{
+			"method": method,
+			"url":    url.String(),
+		}, "%w: %s %s requires an auth token", ErrUnauthorized, method, url.String())
+	}
+
+	tok, err := c.token(ctx, method, url)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, res, err := c.doWithToken(ctx, tok, method, url, opts...)
+	if !errors.Is(err, ErrUnauthorized) {
+		return req, res, err
+	}
+
+	// the token may have been revoked, or expired earlier than expected
+	c.tokenSource.Invalidate(tok)
+	if tok, err = c.token(ctx, method, url); err != nil {
+		return nil, nil, err
+	}
+
+	req, res, err = c.doWithToken(ctx, tok, method, url, opts...)
+	if errors.Is(err, ErrUnauthorized) {
+		c.tokenSource.Invalidate(tok)
+	}
+	return req, res, err
+}
+
+// doWithToken makes a request authenticated with the given bearer token
+func (c *client) doWithToken(ctx context.Context, tok, method string, url *url.URL, opts ...clientlib.RequestOption) (*http.Request, *http.Response, error) {
+	opts = append(append(c.requestDefaults(), bearerAuthOpt(tok)), opts...)
+	return clientlib.Do(jwt.NewContext(ctx, tok), method, url.String(), opts...)
+}
+
+// token returns a token from the client's TokenSource
+func (c *client) token(ctx context.Context, method string, url *url.URL) (string, error) {
+	tok, err := c.tokenSource.Token(ctx, c)
+	if err != nil {
+		return "", errors.Wrapf(err, errors.Fields{
+			"method": method,
+			"url":    url.String(),
+		}, "Failed to get auth token")
+	}
+
+	return tok, nil
 }
 
+// doRequestNoAuth makes an anonymous request. No Authorization header is sent, even if ctx carries a token.
 func (c *client) doRequestNoAuth(ctx context.Context, method string, url *url.URL, opts ...clientlib.RequestOption) (*http.Request, *http.Response, error) {
 	return clientlib.Do(ctx, method, url.String(), append(c.requestDefaults(), opts...)...)
 }
 
+// requestDefaults returns the options shared by authenticated and anonymous requests
 func (c *client) requestDefaults() []clientlib.RequestOption {
 	return []clientlib.RequestOption{
 		func(req *clientlib.Request) {
-			tok, _ := jwt.FromContext(req.Context())
-			req.Header.Add("Authorization", "Bearer "+tok)
-			req.ErrorSummary = errorSummary
+			req.ErrorCheck = errorCheck
+			req.ErrorBodyMaxLength = errBodyMaxLength
 			req.Client = c.hclient
 		},
 	}
 }
 
+// bearerAuthOpt sets the Authorization header of a request
+func bearerAuthOpt(tok string) clientlib.RequestOption {
+	return func(req *clientlib.Request) {
+		req.Header.Set("Authorization", "Bearer "+tok)
+	}
+}
+
 func (c *client) StoreLicense(ctx context.Context, dclnt WrappedDockerClient, licenses *model.IssuedLicense, localRootDir string) error {
 	return StoreLicense(ctx, dclnt, licenses, localRootDir)
 }
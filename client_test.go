@@ -2,19 +2,41 @@ package licensing_test
 
 import (
 	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
 	"path"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/docker/libtrust"
 	"github.com/docker/licensing"
+	"github.com/docker/licensing/lib/go-auth/identity"
+	"github.com/docker/licensing/lib/go-auth/jwt"
+	validation "github.com/docker/licensing/lib/go-validation"
+	"github.com/docker/licensing/mocks"
+	"github.com/docker/licensing/model"
+	"github.com/docker/licensing/types"
 	"github.com/stretchr/testify/require"
 )
 
+// the mock must be regenerated with make mocks when the Client interface changes
+var _ licensing.Client = (*mocks.Client)(nil)
+
 var (
 	mux    *http.ServeMux
 	server *httptest.Server
@@ -48,6 +70,14 @@ func setup() func() {
 	}
 }
 
+func serverURL() *url.URL {
+	parsedURL, err := url.Parse(server.URL)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return parsedURL
+}
+
 func fixture(path string) string {
 	b, err := ioutil.ReadFile("testdata/fixtures/" + path)
 	if err != nil {
@@ -56,82 +86,1483 @@ func fixture(path string) string {
 	return string(b)
 }
 
-func TestClient_GenerateNewTrialSubscription(t *testing.T) {
+func TestClient_LoginWithPassword2FA(t *testing.T) {
 	teardown := setup()
 	defer teardown()
 
 	ctx := context.Background()
 
-	// return account
-	mux.HandleFunc(path.Join("/api/billing/v4/accounts", testDockerID), func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/v2/users/login/", func(w http.ResponseWriter, r *http.Request) {
+		var req model.LoginRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.Equal(t, "testUser", req.Username)
+		require.Equal(t, "testPassword", req.Password)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"detail":"Require secondary authentication on MFA enabled account","login_2fa_token":"test2FAToken"}`)
+	})
+	mux.HandleFunc("/v2/users/2fa-login/", func(w http.ResponseWriter, r *http.Request) {
+		var req model.TwoFactorLoginRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.Equal(t, "test2FAToken", req.Login2FAToken)
+		require.Empty(t, r.Header.Values("Authorization"))
+
 		w.Header().Set("Content-Type", "application/json")
+		if req.Code != "123456" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"detail":"Incorrect authentication credentials"}`)
+			return
+		}
 		w.WriteHeader(http.StatusOK)
-		fmt.Fprint(w, fixture("account.json"))
+		fmt.Fprint(w, `{"token":"testToken"}`)
 	})
 
-	// return new subscription
+	// without a code prompt, the 2fa requirement is reported
+	_, err := client.Login(ctx, &licensing.PasswordCredentials{Username: "testUser", Password: "testPassword"})
+	var loginErr *model.LoginError
+	require.True(t, errors.As(err, &loginErr))
+	require.True(t, loginErr.TwoFactorRequired())
+	require.NotContains(t, err.Error(), "test2FAToken")
+
+	token, err := client.Login(ctx, &licensing.PasswordCredentials{
+		Username: "testUser",
+		Password: "testPassword",
+		TwoFactorCode: func(context.Context) (string, error) {
+			return "123456", nil
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "testToken", token)
+
+	_, err = client.LoginWith2FA(ctx, "test2FAToken", "000000")
+	require.True(t, errors.As(err, &loginErr))
+	require.False(t, loginErr.TwoFactorRequired())
+}
+
+func TestClient_LoginWithAccessToken(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	ctx := context.Background()
+
+	mux.HandleFunc("/v2/users/login/", func(w http.ResponseWriter, r *http.Request) {
+		var req model.LoginRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.Equal(t, "testUser", req.Username)
+		require.Equal(t, "dckr_pat_test", req.Password)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, `{"token":"testToken"}`)
+	})
+
+	token, err := client.Login(ctx, &licensing.AccessTokenCredentials{Username: "testUser", Token: "dckr_pat_test"})
+	require.NoError(t, err)
+	require.Equal(t, "testToken", token)
+}
+
+func TestClient_LoginWithDeviceFlow(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	ctx := context.Background()
+
+	mux.HandleFunc("/oauth/device/code", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		require.Equal(t, "testClientID", r.PostForm.Get("client_id"))
+		require.Equal(t, "openid offline_access", r.PostForm.Get("scope"))
+		require.Equal(t, "https://hub.docker.com", r.PostForm.Get("audience"))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, `{"device_code":"testDeviceCode","user_code":"ABCD-EFGH","verification_uri":"https://login.docker.com/activate","expires_in":900,"interval":5}`)
+	})
+
+	polls := 0
+	mux.HandleFunc("/oauth/token", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		require.Equal(t, "urn:ietf:params:oauth:grant-type:device_code", r.PostForm.Get("grant_type"))
+		require.Equal(t, "testDeviceCode", r.PostForm.Get("device_code"))
+
+		polls++
+		w.Header().Set("Content-Type", "application/json")
+		if polls < 3 {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":"authorization_pending"}`)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, `{"access_token":"testToken","token_type":"Bearer","expires_in":3600}`)
+	})
+
+	var prompted *model.DeviceAuthorization
+	creds := &licensing.DeviceFlowCredentials{
+		DeviceAuthorizationURL: server.URL + "/oauth/device/code",
+		TokenURL:               server.URL + "/oauth/token",
+		ClientID:               "testClientID",
+		Scopes:                 []string{"openid", "offline_access"},
+		Audience:               "https://hub.docker.com",
+		Prompt: func(_ context.Context, auth *model.DeviceAuthorization) error {
+			prompted = auth
+			return nil
+		},
+		PollInterval: time.Millisecond,
+	}
+
+	token, err := client.Login(ctx, creds)
+	require.NoError(t, err)
+	require.Equal(t, "testToken", token)
+	require.Equal(t, 3, polls)
+	require.Equal(t, "ABCD-EFGH", prompted.UserCode)
+}
+
+func TestClient_LoginWithDeviceFlowDenied(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	ctx := context.Background()
+
+	mux.HandleFunc("/oauth/device/code", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, `{"device_code":"testDeviceCode","user_code":"ABCD-EFGH","verification_uri":"https://login.docker.com/activate","expires_in":900}`)
+	})
+	mux.HandleFunc("/oauth/token", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"error":"access_denied","error_description":"User denied the request"}`)
+	})
+
+	_, err := client.Login(ctx, &licensing.DeviceFlowCredentials{
+		DeviceAuthorizationURL: server.URL + "/oauth/device/code",
+		TokenURL:               server.URL + "/oauth/token",
+		ClientID:               "testClientID",
+		PollInterval:           time.Millisecond,
+	})
+	var oauthErr *model.OAuthError
+	require.True(t, errors.As(err, &oauthErr))
+	require.Equal(t, model.OAuthAccessDenied, oauthErr.Code)
+	require.Contains(t, err.Error(), "User denied the request")
+}
+
+// unsignedToken returns a JWT carrying the given expiration, for tests that do not verify tokens
+func unsignedToken(name string, exp time.Time) string {
+	enc := base64.RawURLEncoding
+	claims := fmt.Sprintf(`{"sub":%q,"exp":%d}`, name, exp.Unix())
+	return enc.EncodeToString([]byte(`{"alg":"none"}`)) + "." + enc.EncodeToString([]byte(claims)) + ".sig"
+}
+
+func TestClient_TokenSource(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	ctx := context.Background()
+
+	var logins []string
+	tokenTTL := time.Hour
+	mux.HandleFunc("/v2/users/login/", func(w http.ResponseWriter, r *http.Request) {
+		token := unsignedToken(fmt.Sprintf("login%d", len(logins)), time.Now().Add(tokenTTL))
+		logins = append(logins, token)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, `{"token":%q}`, token)
+	})
+
+	var authorizations []string
+	rejected := map[string]bool{}
+	rejectAll := false
 	mux.HandleFunc("/api/billing/v4/subscriptions", func(w http.ResponseWriter, r *http.Request) {
+		authorization := r.Header.Get("Authorization")
+		authorizations = append(authorizations, authorization)
+
 		w.Header().Set("Content-Type", "application/json")
+		if rejectAll || rejected[authorization] {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"detail":"Token has been revoked"}`)
+			return
+		}
 		w.WriteHeader(http.StatusOK)
-		fmt.Fprint(w, fixture("subscription.json"))
+		fmt.Fprint(w, fixture("subscriptions.json"))
 	})
 
-	subID, err := client.GenerateNewTrialSubscription(ctx, testAuthToken, testDockerID)
+	clnt, err := licensing.New(&licensing.Config{
+		BaseURI: *serverURL(),
+		TokenSource: licensing.NewCredentialsTokenSource(&licensing.AccessTokenCredentials{
+			Username: "testUser",
+			Token:    "dckr_pat_test",
+		}, licensing.TokenSourceOptions{RefreshBefore: time.Minute}),
+	})
 	require.NoError(t, err)
-	require.Equal(t, "testSubscriptionID", subID)
+
+	// the token is cached across calls
+	_, err = clnt.ListSubscriptions(ctx, "", testDockerID)
+	require.NoError(t, err)
+	_, err = clnt.ListSubscriptions(ctx, "", testDockerID)
+	require.NoError(t, err)
+	require.Len(t, logins, 1)
+	require.Equal(t, []string{"Bearer " + logins[0], "Bearer " + logins[0]}, authorizations)
+
+	// explicit tokens are used as is
+	authorizations = nil
+	_, err = clnt.ListSubscriptions(ctx, testAuthToken, testDockerID)
+	require.NoError(t, err)
+	require.Len(t, logins, 1)
+	require.Equal(t, []string{"Bearer " + testAuthToken}, authorizations)
+
+	// a rejected token is replaced, and the request retried once
+	authorizations = nil
+	rejected["Bearer "+logins[0]] = true
+	_, err = clnt.ListSubscriptions(ctx, "", testDockerID)
+	require.NoError(t, err)
+	require.Len(t, logins, 2)
+	require.Equal(t, []string{"Bearer " + logins[0], "Bearer " + logins[1]}, authorizations)
+
+	// but not twice
+	authorizations = nil
+	rejectAll = true
+	_, err = clnt.ListSubscriptions(ctx, "", testDockerID)
+	require.True(t, errors.Is(err, licensing.ErrUnauthorized))
+	require.Len(t, logins, 3)
+	require.Len(t, authorizations, 2)
+
+	// tokens about to expire are refreshed before use
+	rejectAll = false
+	tokenTTL = 30 * time.Second
+	for i := 0; i < 2; i++ {
+		_, err = clnt.ListSubscriptions(ctx, "", testDockerID)
+		require.NoError(t, err)
+	}
+	require.Len(t, logins, 5)
 }
 
-func TestClient_ListSubscriptions(t *testing.T) {
+func TestClient_TokenSourceWithoutExpiration(t *testing.T) {
 	teardown := setup()
 	defer teardown()
 
 	ctx := context.Background()
 
-	// return list of subscriptions
+	logins := 0
+	mux.HandleFunc("/v2/users/login/", func(w http.ResponseWriter, r *http.Request) {
+		logins++
+
+		// exp 0, as jwt.Encode writes for tokens without expiration
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, `{"token":%q}`, unsignedToken("login", time.Unix(0, 0)))
+	})
 	mux.HandleFunc("/api/billing/v4/subscriptions", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
 		fmt.Fprint(w, fixture("subscriptions.json"))
 	})
 
-	subs, err := client.ListSubscriptions(ctx, testAuthToken, testDockerID)
+	clnt, err := licensing.New(&licensing.Config{
+		BaseURI: *serverURL(),
+		TokenSource: licensing.NewCredentialsTokenSource(&licensing.AccessTokenCredentials{
+			Username: "testUser",
+			Token:    "dckr_pat_test",
+		}, licensing.TokenSourceOptions{}),
+	})
 	require.NoError(t, err)
-	// test filter, should only be one sub with 'docker-ee' prefix
-	require.Len(t, subs, 1)
+
+	// tokens without expiration are cached until rejected
+	for i := 0; i < 3; i++ {
+		_, err = clnt.ListSubscriptions(ctx, "", testDockerID)
+		require.NoError(t, err)
+	}
+	require.Equal(t, 1, logins)
 }
 
-func TestClient_VerifyLicense(t *testing.T) {
+// signedToken returns a JWT signed with an Ed25519 key with id "test", and a key set holding the key
+func signedToken(t *testing.T, exp time.Time) (string, jwt.KeySet) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	require.NoError(t, err)
+
+	token, err := jwt.Encode(identity.DockerIdentity{DockerID: testDockerID, Username: "testUser"}, jwt.EncodeOptions{
+		SigningKey: pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}),
+		Algorithm:  jwt.EdDSA,
+		KeyID:      "test",
+		Expiration: exp.Unix(),
+	})
+	require.NoError(t, err)
+
+	jwk, err := jwt.NewJSONWebKey("test", public)
+	require.NoError(t, err)
+	keySet, err := jwt.NewStaticKeySet(&jwt.JSONWebKeySet{Keys: []jwt.JSONWebKey{jwk}})
+	require.NoError(t, err)
+	return token, keySet
+}
+
+func TestClient_TokenSourceWithKeySet(t *testing.T) {
 	teardown := setup()
 	defer teardown()
 
 	ctx := context.Background()
 
-	licBytes, err := ioutil.ReadFile("testdata/test-license.lic")
+	token, keySet := signedToken(t, time.Now().Add(time.Hour))
+	untrusted, _ := signedToken(t, time.Now().Add(time.Hour))
+
+	logins := 0
+	mux.HandleFunc("/v2/users/login/", func(w http.ResponseWriter, r *http.Request) {
+		logins++
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if logins == 1 {
+			fmt.Fprintf(w, `{"token":%q}`, untrusted)
+			return
+		}
+		fmt.Fprintf(w, `{"token":%q}`, token)
+	})
+
+	source := licensing.NewCredentialsTokenSource(&licensing.AccessTokenCredentials{
+		Username: "testUser",
+		Token:    "dckr_pat_test",
+	}, licensing.TokenSourceOptions{DecodeOptions: jwt.DecodeOptions{KeySet: keySet}})
+
+	// tokens failing verification with the key set are not reused
+	got, err := source.Token(ctx, client)
 	require.NoError(t, err)
+	require.Equal(t, untrusted, got)
 
-	lic, err := client.ParseLicense(licBytes)
+	for i := 0; i < 2; i++ {
+		got, err = source.Token(ctx, client)
+		require.NoError(t, err)
+		require.Equal(t, token, got)
+	}
+	require.Equal(t, 2, logins)
+}
+
+func TestClient_TokenSourceConcurrentLogin(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	logins := 0
+	started := make(chan struct{})
+	release := make(chan struct{})
+	mux.HandleFunc("/v2/users/login/", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		logins++
+		if logins == 1 {
+			close(started)
+		}
+		mu.Unlock()
+		<-release
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, `{"token":%q}`, unsignedToken("login", time.Now().Add(time.Hour)))
+	})
+
+	source := licensing.NewCredentialsTokenSource(&licensing.AccessTokenCredentials{
+		Username: "testUser",
+		Token:    "dckr_pat_test",
+	}, licensing.TokenSourceOptions{})
+
+	var wg sync.WaitGroup
+	tokens := make([]string, 5)
+	errs := make([]error, len(tokens))
+	for i := range tokens {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tokens[i], errs[i] = source.Token(ctx, client)
+		}(i)
+	}
+
+	// the source is not locked during the login
+	<-started
+	invalidated := make(chan struct{})
+	go func() {
+		source.Invalidate("other")
+		close(invalidated)
+	}()
+	select {
+	case <-invalidated:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Invalidate blocked by the login")
+	}
+
+	close(release)
+	wg.Wait()
+
+	for i := range tokens {
+		require.NoError(t, errs[i])
+		require.Equal(t, tokens[0], tokens[i])
+	}
+	require.Equal(t, 1, logins)
+}
+
+func TestClient_RequestAuthorization(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	// anonymous endpoints must not forward a token carried by the context
+	ctx := jwt.NewContext(context.Background(), "contextToken")
+
+	var authorization []string
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Values("Authorization")
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/v2/user/orgs/"):
+			fmt.Fprint(w, `{"results":[]}`)
+		case r.URL.Path == "/api/billing/v4/subscriptions" && r.Method == "GET":
+			fmt.Fprint(w, `[]`)
+		default:
+			fmt.Fprint(w, `{}`)
+		}
+	})
+
+	for _, tc := range []struct {
+		name          string
+		authenticated bool
+		call          func() error
+	}{
+		{"LoginViaAuth", false, func() error {
+			_, err := client.LoginViaAuth(ctx, "testUser", "testPassword")
+			return err
+		}},
+		{"LoginWith2FA", false, func() error {
+			_, err := client.LoginWith2FA(ctx, "test2FAToken", "123456")
+			return err
+		}},
+		{"GetHubUserByName", false, func() error {
+			_, err := client.GetHubUserByName(ctx, "testUser")
+			return err
+		}},
+		{"GetHubUserOrgs", true, func() error {
+			_, err := client.GetHubUserOrgs(ctx, testAuthToken)
+			return err
+		}},
+		{"GenerateNewTrialSubscription", true, func() error {
+			_, err := client.GenerateNewTrialSubscription(ctx, testAuthToken, testDockerID)
+			return err
+		}},
+		{"CheckTrialEligibility", true, func() error {
+			_, err := client.CheckTrialEligibility(ctx, testAuthToken, testDockerID)
+			return err
+		}},
+		{"ListSubscriptions", true, func() error {
+			_, err := client.ListSubscriptions(ctx, testAuthToken, testDockerID)
+			return err
+		}},
+		{"ListSubscriptionsDetails", true, func() error {
+			_, err := client.ListSubscriptionsDetails(ctx, testAuthToken, testDockerID)
+			return err
+		}},
+		{"GetSubscription", true, func() error {
+			_, err := client.GetSubscription(ctx, testAuthToken, "testSubscriptionID")
+			return err
+		}},
+		{"CancelSubscription", true, func() error {
+			_, err := client.CancelSubscription(ctx, testAuthToken, "testSubscriptionID", nil)
+			return err
+		}},
+		{"RenewSubscription", true, func() error {
+			_, err := client.RenewSubscription(ctx, testAuthToken, "testSubscriptionID", nil)
+			return err
+		}},
+		{"DownloadLicenseFromHub", true, func() error {
+			// the empty license does not parse, only the request matters here
+			client.DownloadLicenseFromHub(ctx, testAuthToken, "testSubscriptionID")
+			return nil
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			authorization = nil
+			require.NoError(t, tc.call())
+			if tc.authenticated {
+				require.Equal(t, []string{"Bearer " + testAuthToken}, authorization)
+			} else {
+				require.Empty(t, authorization)
+			}
+		})
+	}
+}
+
+func TestClient_MissingAuthToken(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	ctx := context.Background()
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		t.Fatalf("unexpected request %s %s", r.Method, r.URL)
+	})
+
+	_, err := client.ListSubscriptions(ctx, "", testDockerID)
+	require.True(t, errors.Is(err, licensing.ErrMissingAuthToken))
+	require.True(t, errors.Is(err, licensing.ErrUnauthorized))
+
+	_, err = client.GetHubUserOrgs(ctx, "")
+	require.True(t, errors.Is(err, licensing.ErrMissingAuthToken))
+}
+
+// stubCredentialHelper installs a docker-credential-stub helper on the PATH, storing the given credentials for
+// the hub only
+func stubCredentialHelper(t *testing.T, username, secret string) {
+	dir := t.TempDir()
+	script := fmt.Sprintf(`#!/bin/sh
+[ "$1" = "get" ] || exit 2
+read server
+if [ "$server" = "https://index.docker.io/v1/" ]; then
+	echo '{"ServerURL":"'$server'","Username":"%s","Secret":"%s"}'
+	exit 0
+fi
+echo "credentials not found in native keychain"
+exit 1
+`, username, secret)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "docker-credential-stub"), []byte(script), 0755))
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+// testIdentityToken has the shape of the identity tokens docker logins store: an opaque registry OAuth refresh
+// token, not a JWT
+const testIdentityToken = "9cbaf023786cd7a2da4c0d3e5e7b1b7d6eab5c9d1f1e2a3b4c5d6e7f8091a2b3"
+
+func writeDockerConfig(t *testing.T, config string) string {
+	path := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, ioutil.WriteFile(path, []byte(config), 0600))
+	return path
+}
+
+func TestClient_LoginWithDockerConfig(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	ctx := context.Background()
+
+	var logins []model.LoginRequest
+	mux.HandleFunc("/v2/users/login/", func(w http.ResponseWriter, r *http.Request) {
+		var req model.LoginRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		logins = append(logins, req)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, `{"token":"testToken"}`)
+	})
+
+	stubCredentialHelper(t, "helperUser", "dckr_pat_helper")
+
+	for _, tc := range []struct {
+		name          string
+		config        string
+		serverAddress string
+		expectedToken string
+		expectedLogin *model.LoginRequest
+		expectedErr   error
+	}{
+		{
+			name:          "config file auth",
+			config:        `{"auths":{"https://index.docker.io/v1/":{"auth":"` + base64.StdEncoding.EncodeToString([]byte("fileUser:dckr_pat_file")) + `"}}}`,
+			expectedToken: "testToken",
+			expectedLogin: &model.LoginRequest{Username: "fileUser", Password: "dckr_pat_file"},
+		},
+		{
+			name:        "config file identity token",
+			config:      `{"auths":{"docker.io":{"identitytoken":"` + testIdentityToken + `"}}}`,
+			expectedErr: licensing.ErrIdentityTokenNotSupported,
+		},
+		{
+			name:          "credential store",
+			config:        `{"auths":{"https://index.docker.io/v1/":{}},"credsStore":"stub"}`,
+			expectedToken: "testToken",
+			expectedLogin: &model.LoginRequest{Username: "helperUser", Password: "dckr_pat_helper"},
+		},
+		{
+			name:          "server credential helper",
+			config:        `{"credsStore":"missing","credHelpers":{"index.docker.io":"stub"}}`,
+			expectedToken: "testToken",
+			expectedLogin: &model.LoginRequest{Username: "helperUser", Password: "dckr_pat_helper"},
+		},
+		{
+			name:          "credential helper without credentials",
+			config:        `{"credsStore":"stub"}`,
+			serverAddress: "registry.example.com",
+			expectedErr:   licensing.ErrCredentialsNotFound,
+		},
+		{
+			name:        "config file without credentials",
+			config:      `{"auths":{"registry.example.com":{"auth":"` + base64.StdEncoding.EncodeToString([]byte("user:password")) + `"}}}`,
+			expectedErr: licensing.ErrCredentialsNotFound,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			logins = nil
+			token, err := client.Login(ctx, &licensing.DockerConfigCredentials{
+				ConfigFile:    writeDockerConfig(t, tc.config),
+				ServerAddress: tc.serverAddress,
+			})
+			if tc.expectedErr != nil {
+				require.True(t, errors.Is(err, tc.expectedErr), "unexpected error %v", err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedToken, token)
+			if tc.expectedLogin == nil {
+				require.Empty(t, logins)
+			} else {
+				require.Equal(t, []model.LoginRequest{*tc.expectedLogin}, logins)
+			}
+		})
+	}
+
+	// DOCKER_CONFIG locates the default config file
+	dir := t.TempDir()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "config.json"), []byte(`{"credsStore":"stub"}`), 0600))
+	t.Setenv("DOCKER_CONFIG", dir)
+
+	creds, err := (&licensing.DockerConfigCredentials{}).Lookup(ctx)
 	require.NoError(t, err)
+	require.Equal(t, "helperUser", creds.Username)
+	require.NotContains(t, fmt.Sprintf("%v %#v", creds, creds), "dckr_pat_helper")
+}
 
-	_, err = client.VerifyLicense(ctx, *lic)
+func TestClient_LoginWithDockerConfigIdentityToken(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	ctx := context.Background()
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		t.Fatalf("unexpected request %s %s", r.Method, r.URL)
+	})
+
+	// credential helpers store identity tokens under the <token> username
+	stubCredentialHelper(t, "<token>", testIdentityToken)
+
+	creds := &licensing.DockerConfigCredentials{
+		ConfigFile: writeDockerConfig(t, `{"credsStore":"stub"}`),
+	}
+	stored, err := creds.Lookup(ctx)
 	require.NoError(t, err)
+	require.True(t, stored.IsIdentityToken())
+
+	token, err := client.Login(ctx, creds)
+	require.True(t, errors.Is(err, licensing.ErrIdentityTokenNotSupported), "unexpected error %v", err)
+	require.Empty(t, token)
+	require.NotContains(t, err.Error(), testIdentityToken)
 }
 
-func TestClient_SummarizeLicense(t *testing.T) {
+func TestClient_GetHubUserOrgs(t *testing.T) {
 	teardown := setup()
 	defer teardown()
 
 	ctx := context.Background()
 
-	licBytes, err := ioutil.ReadFile("testdata/expired-license.lic")
+	var requests []string
+	mux.HandleFunc("/v2/user/orgs/", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Bearer "+testAuthToken, r.Header.Get("Authorization"))
+		requests = append(requests, r.URL.RawQuery)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		switch r.URL.Query().Get("page") {
+		case "":
+			// next links are absolute urls of the public hub host
+			fmt.Fprint(w, `{"count":3,"next":"https://hub.docker.com/v2/user/orgs/?page=2&page_size=2","results":[{"orgname":"org1"},{"orgname":"org2"}]}`)
+		case "2":
+			fmt.Fprint(w, `{"count":3,"next":null,"results":[{"orgname":"org3"}]}`)
+		}
+	})
+
+	orgs, err := client.AllHubUserOrgs(ctx, testAuthToken, 2)
 	require.NoError(t, err)
+	require.Len(t, orgs, 3)
+	require.Equal(t, "org3", orgs[2].Orgname)
+	require.Equal(t, []string{"page_size=2", "page=2&page_size=2"}, requests)
 
-	lic, err := client.ParseLicense(licBytes)
+	requests = nil
+	it := client.IterateHubUserOrgs(ctx, testAuthToken, 2)
+	require.True(t, it.Next())
+	require.Equal(t, "org1", it.Value().Orgname)
+	require.Equal(t, []string{"page_size=2"}, requests)
+
+	requests = nil
+	orgs, err = client.GetHubUserOrgs(ctx, testAuthToken)
 	require.NoError(t, err)
+	require.Len(t, orgs, 3)
+	require.Equal(t, []string{"", "page=2&page_size=2"}, requests)
+}
 
-	cr, err := client.VerifyLicense(ctx, *lic)
+func TestClient_GetHubUserOrgsError(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	ctx := context.Background()
+
+	mux.HandleFunc("/v2/user/orgs/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("page") == "2" {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, `{"detail":"boom"}`)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, `{"count":2,"next":"/v2/user/orgs/?page=2","results":[{"orgname":"org1"}]}`)
+	})
+
+	it := client.IterateHubUserOrgs(ctx, testAuthToken, 0)
+	require.True(t, it.Next())
+	require.False(t, it.Next())
+	require.Error(t, it.Err())
+	require.Contains(t, it.Err().Error(), "boom")
+
+	_, err := client.GetHubUserOrgs(ctx, testAuthToken)
+	require.Error(t, err)
+}
+
+func TestClient_GenerateNewTrialSubscription(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	ctx := context.Background()
+
+	// return account
+	mux.HandleFunc(path.Join("/api/billing/v4/accounts", testDockerID), func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, fixture("account.json"))
+	})
+
+	// return new subscription
+	mux.HandleFunc("/api/billing/v4/subscriptions", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, fixture("subscription.json"))
+	})
+
+	subID, err := client.GenerateNewTrialSubscription(ctx, testAuthToken, testDockerID)
 	require.NoError(t, err)
+	require.Equal(t, "testSubscriptionID", subID)
+}
 
-	summary := client.SummarizeLicense(cr).String()
-	expected := "Components: 1 Nodes	Expiration date: 2018-03-18	Expired! You will no longer receive updates. Please renew at https://docker.com/licensing"
-	require.Equal(t, summary, expected)
+func TestClient_GenerateNewTrialSubscriptionConflict(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	ctx := context.Background()
+
+	mux.HandleFunc("/api/billing/v4/subscriptions", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		fmt.Fprint(w, `{"message":"user has already had a trial"}`)
+	})
+
+	_, err := client.GenerateNewTrialSubscription(ctx, testAuthToken, testDockerID)
+	require.Error(t, err)
+	require.True(t, errors.Is(err, licensing.ErrTrialIneligible))
+	require.True(t, errors.Is(err, licensing.ErrConflict))
+
+	var conflict *licensing.ConflictError
+	require.True(t, errors.As(err, &conflict))
+	require.Equal(t, http.StatusConflict, conflict.Status)
+	require.Contains(t, err.Error(), "user has already had a trial")
+}
+
+func TestClient_CheckTrialEligibility(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	ctx := context.Background()
+
+	var subs string
+	mux.HandleFunc("/api/billing/v4/subscriptions", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, testDockerID, r.URL.Query().Get("docker_id"))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, subs)
+	})
+
+	for _, tc := range []struct {
+		name     string
+		subs     string
+		expected model.TrialEligibility
+	}{
+		{
+			name:     "no subscriptions",
+			subs:     `[]`,
+			expected: model.TrialEligibility{Eligible: true},
+		},
+		{
+			name: "expired trial",
+			subs: `[{"subscription_id":"trialSubID","docker_id":"testDockerID","product_id":"docker-ee-trial","product_rate_plan":"free-trial","state":"expired"}]`,
+			expected: model.TrialEligibility{
+				Reason:         model.TrialAlreadyUsed,
+				SubscriptionID: "trialSubID",
+			},
+		},
+		{
+			name: "active subscription",
+			subs: fixture("subscriptions.json"),
+			expected: model.TrialEligibility{
+				Reason:         model.TrialHasSubscription,
+				SubscriptionID: "testSubID1",
+			},
+		},
+		{
+			name: "cancelled subscription and org trial",
+			subs: `[
+				{"subscription_id":"cancelledSubID","docker_id":"testDockerID","product_id":"docker-ee","product_rate_plan":"nfr","state":"cancelled"},
+				{"subscription_id":"orgTrialSubID","docker_id":"testOrgID","product_id":"docker-ee-trial","product_rate_plan":"free-trial","state":"active"}
+			]`,
+			expected: model.TrialEligibility{Eligible: true},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			subs = tc.subs
+			eligibility, err := client.CheckTrialEligibility(ctx, testAuthToken, testDockerID)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, *eligibility)
+		})
+	}
+}
+
+func TestClient_CreateSubscription(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	ctx := context.Background()
+
+	var received model.SubscriptionCreationRequest
+	mux.HandleFunc("/api/billing/v4/subscriptions", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "POST", r.Method)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, fixture("subscription.json"))
+	})
+
+	start := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(1, 0, 0)
+	sub, err := client.CreateSubscription(ctx, testAuthToken, &model.SubscriptionCreationRequest{
+		Name:              "Docker Enterprise",
+		DockerID:          testDockerID,
+		ProductID:         "docker-ee",
+		ProductRatePlan:   "nfr-annual",
+		Origin:            "partner",
+		Start:             &start,
+		End:               &end,
+		CouponCodes:       []string{"WELCOME"},
+		PricingComponents: model.PricingComponents{{Name: "Nodes", Value: 10}},
+		ManagingPartnerID: "testPartnerID",
+		PartnerAccountID:  "testPartnerAccountID",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "testSubscriptionID", sub.ID)
+
+	require.Equal(t, "nfr-annual", received.ProductRatePlan)
+	require.Equal(t, "partner", received.Origin)
+	require.Equal(t, []string{"WELCOME"}, received.CouponCodes)
+	require.Equal(t, "testPartnerAccountID", received.PartnerAccountID)
+	require.True(t, end.Equal(*received.End))
+}
+
+func TestClient_CreateSubscriptionValidation(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	ctx := context.Background()
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		t.Fatalf("unexpected request %s %s", r.Method, r.URL)
+	})
+
+	start := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	_, err := client.CreateSubscription(ctx, testAuthToken, &model.SubscriptionCreationRequest{
+		DockerID:          testDockerID,
+		ProductID:         "docker-ee",
+		ProductRatePlan:   "nfr-annual",
+		Start:             &start,
+		End:               &start,
+		PricingComponents: model.PricingComponents{{Name: "Nodes", Value: -1}},
+		PartnerAccountID:  "testPartnerAccountID",
+	})
+
+	var verrs validation.Errors
+	require.True(t, errors.As(err, &verrs))
+
+	names := make([]string, len(verrs))
+	for i, verr := range verrs {
+		names[i] = verr.FieldName
+	}
+	require.Equal(t, []string{"name", "end", "pricing_component[0]/value", "managing_partner_id"}, names)
+}
+
+func TestClient_ListSubscriptions(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	ctx := context.Background()
+
+	// return list of subscriptions
+	mux.HandleFunc("/api/billing/v4/subscriptions", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, fixture("subscriptions.json"))
+	})
+
+	subs, err := client.ListSubscriptions(ctx, testAuthToken, testDockerID)
+	require.NoError(t, err)
+	// test filter, should only be one sub with 'docker-ee' prefix
+	require.Len(t, subs, 1)
+}
+
+func TestClient_QuerySubscriptions(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	ctx := context.Background()
+
+	var query url.Values
+	mux.HandleFunc("/api/billing/v4/subscriptions", func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, fixture("subscriptions.json"))
+	})
+
+	after := time.Date(2018, 8, 1, 0, 0, 0, 0, time.UTC)
+	subs, err := client.QuerySubscriptions(ctx, testAuthToken, &model.SubscriptionQuery{
+		DockerID:        testDockerID,
+		Origin:          "docker",
+		States:          []types.State{types.Active, types.Expired},
+		ProductIDPrefix: "docker",
+		RatePlans:       []string{"testRatePlan"},
+		StartAfter:      &after,
+		Sort:            model.SortByExpires,
+		Descending:      true,
+		Page:            2,
+		PageSize:        10,
+	})
+	require.NoError(t, err)
+	require.Len(t, subs, 1)
+	require.Equal(t, "testSubID1", subs[0].ID)
+
+	// filters the billing service does not support are applied by the client
+	require.Equal(t, url.Values{
+		"docker_id": {testDockerID},
+		"origin":    {"docker"},
+		"page":      {"2"},
+		"page_size": {"10"},
+	}, query)
+
+	_, err = client.QuerySubscriptions(ctx, testAuthToken, &model.SubscriptionQuery{
+		States:   []types.State{"unknown"},
+		Sort:     "price",
+		PageSize: -1,
+	})
+	var verrs validation.Errors
+	require.True(t, errors.As(err, &verrs))
+	require.Len(t, verrs, 3)
+
+	after, before := time.Now(), time.Now().Add(-time.Hour)
+	_, err = client.QuerySubscriptions(ctx, testAuthToken, &model.SubscriptionQuery{
+		ExpiresAfter:  &after,
+		ExpiresBefore: &before,
+	})
+	require.True(t, errors.As(err, &verrs))
+	require.Len(t, verrs, 1)
+	require.Equal(t, "expires_before", verrs[0].FieldName)
+}
+
+func TestClient_QuerySubscriptionsSort(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	ctx := context.Background()
+
+	mux.HandleFunc("/api/billing/v4/subscriptions", func(w http.ResponseWriter, r *http.Request) {
+		require.Empty(t, r.URL.Query().Get("sort"))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, `[
+			{"subscription_id":"b","name":"beta","state":"expired","current_period_end":"2019-01-01T00:00:00Z"},
+			{"subscription_id":"none","name":"gamma","state":"active"},
+			{"subscription_id":"a","name":"alpha","state":"active","current_period_end":"2020-01-01T00:00:00Z"},
+			{"subscription_id":"c","name":"delta","state":"active","current_period_end":"2018-01-01T00:00:00Z"}
+		]`)
+	})
+
+	ids := func(query *model.SubscriptionQuery) []string {
+		subs, err := client.QuerySubscriptions(ctx, testAuthToken, query)
+		require.NoError(t, err)
+		ids := make([]string, len(subs))
+		for i, sub := range subs {
+			ids[i] = sub.ID
+		}
+		return ids
+	}
+
+	require.Equal(t, []string{"b", "none", "a", "c"}, ids(&model.SubscriptionQuery{}))
+	require.Equal(t, []string{"a", "b", "c", "none"}, ids(&model.SubscriptionQuery{Sort: model.SortByName}))
+	require.Equal(t, []string{"none", "c", "b", "a"}, ids(&model.SubscriptionQuery{Sort: model.SortByName, Descending: true}))
+	// subscriptions missing the sorted time come last, either way
+	require.Equal(t, []string{"c", "b", "a", "none"}, ids(&model.SubscriptionQuery{Sort: model.SortByExpires}))
+	require.Equal(t, []string{"a", "b", "c", "none"}, ids(&model.SubscriptionQuery{Sort: model.SortByExpires, Descending: true}))
+	// sorting is stable
+	require.Equal(t, []string{"none", "a", "c", "b"}, ids(&model.SubscriptionQuery{Sort: model.SortByState}))
+	// and applies to filtered subscriptions
+	require.Equal(t, []string{"c", "a", "none"}, ids(&model.SubscriptionQuery{
+		States: []types.State{types.Active},
+		Sort:   model.SortByExpires,
+	}))
+}
+
+func TestClient_IterateSubscriptions(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	ctx := context.Background()
+
+	var pages []string
+	mux.HandleFunc("/api/billing/v4/subscriptions", func(w http.ResponseWriter, r *http.Request) {
+		page := r.URL.Query().Get("page")
+		require.Equal(t, "2", r.URL.Query().Get("page_size"))
+		pages = append(pages, page)
+
+		var subs []string
+		switch page {
+		case "1":
+			subs = []string{"sub1", "sub2"}
+		case "2":
+			subs = []string{"sub3", "sub4"}
+		case "3":
+			subs = []string{"sub5"}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, "[")
+		for i, id := range subs {
+			if i > 0 {
+				fmt.Fprint(w, ",")
+			}
+			state := "active"
+			if id == "sub4" {
+				state = "expired"
+			}
+			fmt.Fprintf(w, `{"subscription_id":%q,"product_id":"docker-ee","state":%q}`, id, state)
+		}
+		fmt.Fprint(w, "]")
+	})
+
+	it := client.IterateSubscriptions(ctx, testAuthToken, &model.SubscriptionQuery{
+		DockerID: testDockerID,
+		States:   []types.State{types.Active},
+		PageSize: 2,
+	})
+
+	var ids []string
+	for it.Next() {
+		ids = append(ids, it.Subscription().ID)
+	}
+	require.NoError(t, it.Err())
+	require.Equal(t, []string{"sub1", "sub2", "sub3", "sub5"}, ids)
+	require.Equal(t, []string{"1", "2", "3"}, pages)
+}
+
+func TestClient_IterateSubscriptionsUnpaginated(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	ctx := context.Background()
+
+	requests := 0
+	mux.HandleFunc("/api/billing/v4/subscriptions", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, `[{"subscription_id":"sub1"},{"subscription_id":"sub2"}]`)
+	})
+
+	it := client.IterateSubscriptions(ctx, testAuthToken, &model.SubscriptionQuery{PageSize: 2})
+
+	var ids []string
+	for it.Next() {
+		ids = append(ids, it.Subscription().ID)
+	}
+	require.NoError(t, it.Err())
+	require.Equal(t, []string{"sub1", "sub2"}, ids)
+	require.Equal(t, 2, requests)
+
+	// pages cannot be sorted across
+	it = client.IterateSubscriptions(ctx, testAuthToken, &model.SubscriptionQuery{Sort: model.SortByName})
+	require.False(t, it.Next())
+	var verrs validation.Errors
+	require.True(t, errors.As(it.Err(), &verrs))
+	require.Equal(t, 2, requests)
+}
+
+func TestClient_ListAccessibleSubscriptions(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	ctx := context.Background()
+
+	clnt, err := licensing.New(&licensing.Config{
+		BaseURI:     *serverURL(),
+		Concurrency: 2,
+	})
+	require.NoError(t, err)
+
+	mux.HandleFunc("/v2/user/orgs/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, `{"count":3,"results":[{"id":"org1ID","orgname":"org1"},{"orgname":"org2"},{"id":"org3ID","orgname":"org3"}]}`)
+	})
+	mux.HandleFunc("/v2/users/org2/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, `{"id":"org2ID","username":"org2"}`)
+	})
+
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	mux.HandleFunc("/api/billing/v4/subscriptions", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+		}()
+		time.Sleep(10 * time.Millisecond)
+
+		dockerID := r.URL.Query().Get("docker_id")
+		w.Header().Set("Content-Type", "application/json")
+		if dockerID == "org3ID" {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, `{"detail":"billing unavailable"}`)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, `[{"subscription_id":"%sSub","docker_id":%q,"product_id":"docker-ee"},{"subscription_id":"other","product_id":"other"}]`, dockerID, dockerID)
+	})
+
+	res, err := clnt.ListAccessibleSubscriptions(ctx, testAuthToken, testDockerID)
+	require.NoError(t, err)
+	require.Len(t, res.Owners, 4)
+	require.True(t, maxInFlight <= 2, "max in flight requests %d", maxInFlight)
+
+	for i, expected := range []struct{ dockerID, orgname string }{
+		{testDockerID, ""},
+		{"org1ID", "org1"},
+		{"org2ID", "org2"},
+	} {
+		owner := res.Owners[i]
+		require.NoError(t, owner.Err)
+		require.Equal(t, expected.dockerID, owner.DockerID)
+		require.Equal(t, expected.orgname, owner.Orgname)
+		require.Len(t, owner.Subscriptions, 1)
+		require.Equal(t, expected.dockerID+"Sub", owner.Subscriptions[0].ID)
+	}
+
+	failed := res.Failed()
+	require.Len(t, failed, 1)
+	require.Equal(t, "org3", failed[0].Orgname)
+	require.Nil(t, failed[0].Subscriptions)
+	require.Contains(t, res.Err().Error(), "billing unavailable")
+
+	// failures are kept when the result is serialized
+	b, err := json.Marshal(res)
+	require.NoError(t, err)
+	var decoded model.AccessibleSubscriptions
+	require.NoError(t, json.Unmarshal(b, &decoded))
+	require.Empty(t, decoded.Owners[0].Error)
+	require.Equal(t, failed[0].Err.Error(), decoded.Owners[3].Error)
+	require.Contains(t, decoded.Owners[3].Error, "billing unavailable")
+	require.Len(t, decoded.Failed(), 1)
+	require.Equal(t, res.Err().Error(), decoded.Err().Error())
+}
+
+func TestClient_VerifyLicense(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	ctx := context.Background()
+
+	licBytes, err := ioutil.ReadFile("testdata/test-license.lic")
+	require.NoError(t, err)
+
+	lic, err := client.ParseLicense(licBytes)
+	require.NoError(t, err)
+
+	_, err = client.VerifyLicense(ctx, *lic)
+	require.NoError(t, err)
+}
+
+func TestClient_SummarizeLicense(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	ctx := context.Background()
+
+	licBytes, err := ioutil.ReadFile("testdata/expired-license.lic")
+	require.NoError(t, err)
+
+	lic, err := client.ParseLicense(licBytes)
+	require.NoError(t, err)
+
+	cr, err := client.VerifyLicense(ctx, *lic)
+	require.NoError(t, err)
+
+	summary := client.SummarizeLicense(cr).String()
+	expected := "Components: 1 Nodes	Expiration date: 2018-03-18	Expired! You will no longer receive updates. Please renew at https://docker.com/licensing"
+	require.Equal(t, summary, expected)
+}
+
+func TestClient_VerifyLicenseUnrecognizedKey(t *testing.T) {
+	key, err := libtrust.GenerateECP256PrivateKey()
+	require.NoError(t, err)
+	block, err := key.PublicKey().PEMBlock()
+	require.NoError(t, err)
+
+	c, err := licensing.New(&licensing.Config{
+		PublicKeys: []string{base64.StdEncoding.EncodeToString(pem.EncodeToMemory(block))},
+	})
+	require.NoError(t, err)
+
+	licBytes, err := ioutil.ReadFile("testdata/test-license.lic")
+	require.NoError(t, err)
+
+	lic, err := c.ParseLicense(licBytes)
+	require.NoError(t, err)
+
+	_, err = c.VerifyLicense(context.Background(), *lic)
+	require.Error(t, err)
+	require.True(t, errors.Is(err, licensing.ErrUnrecognizedSigningKey))
+
+	var verr *licensing.VerificationError
+	require.True(t, errors.As(err, &verr))
+	require.Equal(t, lic.KeyID, verr.KeyID)
+}
+
+func TestClient_DownloadLicenseNotFound(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/billing/v4/subscriptions/unknown/license-file", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message": "subscription not found"}`)
+	})
+
+	_, err := client.DownloadLicenseFromHub(context.Background(), testAuthToken, "unknown")
+	require.Error(t, err)
+	require.True(t, errors.Is(err, licensing.ErrSubscriptionNotFound))
+	require.True(t, errors.Is(err, licensing.ErrNotFound))
+	require.False(t, errors.Is(err, licensing.ErrUnauthorized))
+}
+
+func TestClient_Unauthorized(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/billing/v4/subscriptions", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"message": "token is expired"}`)
+	})
+
+	_, err := client.ListSubscriptions(context.Background(), testAuthToken, testDockerID)
+	require.Error(t, err)
+	require.True(t, errors.Is(err, licensing.ErrUnauthorized))
+	require.True(t, errors.Is(err, licensing.ErrTokenExpired))
+
+	var aerr *licensing.AuthError
+	require.True(t, errors.As(err, &aerr))
+	require.Equal(t, http.StatusUnauthorized, aerr.HTTPStatus())
+}
+
+func TestClient_TransportError(t *testing.T) {
+	teardown := setup()
+	teardown()
+
+	_, err := client.ListSubscriptions(context.Background(), testAuthToken, testDockerID)
+	require.Error(t, err)
+
+	var terr *licensing.TransportError
+	require.True(t, errors.As(err, &terr))
+	require.Equal(t, "GET", terr.Method)
+}
+
+func TestClient_ProblemJSONError(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/billing/v4/subscriptions", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{
+			"type": "https://docs.docker.com/errors/invalid-request",
+			"title": "Invalid request",
+			"status": 400,
+			"detail": "The request parameters are invalid",
+			"instance": "/api/billing/v4/subscriptions",
+			"invalid-params": [{"name": "docker_id", "reason": "must not be empty"}],
+			"trace_id": "abc"
+		}`)
+	})
+
+	_, err := client.ListSubscriptions(context.Background(), testAuthToken, testDockerID)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "The request parameters are invalid; docker_id: must not be empty")
+
+	var apiErr *model.APIError
+	require.True(t, errors.As(err, &apiErr))
+	require.Equal(t, "https://docs.docker.com/errors/invalid-request", apiErr.Type)
+	require.Equal(t, "Invalid request", apiErr.Title)
+	require.Equal(t, http.StatusBadRequest, apiErr.Status)
+	require.Equal(t, "/api/billing/v4/subscriptions", apiErr.Instance)
+	require.Equal(t, []string{"must not be empty"}, apiErr.FieldErrors["docker_id"])
+	require.Equal(t, "abc", apiErr.Extensions["trace_id"])
+}
+
+func TestClient_HubValidationError(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/billing/v4/subscriptions", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"detail": "You do not have permission", "docker_id": ["not a member"]}`)
+	})
+
+	_, err := client.ListSubscriptions(context.Background(), testAuthToken, testDockerID)
+	require.True(t, errors.Is(err, licensing.ErrForbidden))
+
+	var apiErr *model.APIError
+	require.True(t, errors.As(err, &apiErr))
+	require.Equal(t, "You do not have permission", apiErr.Detail)
+	require.Equal(t, []string{"not a member"}, apiErr.FieldErrors["docker_id"])
+}
+
+func TestClient_TextError(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/api/billing/v4/subscriptions", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusBadGateway)
+		fmt.Fprint(w, strings.Repeat("x", 1024))
+	})
+
+	_, err := client.ListSubscriptions(context.Background(), testAuthToken, testDockerID)
+	require.Error(t, err)
+
+	var apiErr *model.APIError
+	require.True(t, errors.As(err, &apiErr))
+	require.Len(t, apiErr.Detail, 256)
+}
+
+func TestClient_SubscriptionLifecycle(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	ctx := context.Background()
+
+	var requests []string
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		requests = append(requests, r.Method+" "+r.URL.Path+" "+string(body))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, fixture("subscription.json"))
+	}
+	mux.HandleFunc("/api/billing/v4/subscriptions/testSubscriptionID", handler)
+	mux.HandleFunc("/api/billing/v4/subscriptions/testSubscriptionID/", handler)
+
+	sub, err := client.GetSubscription(ctx, testAuthToken, "testSubscriptionID")
+	require.NoError(t, err)
+	require.Equal(t, "testSubscriptionID", sub.ID)
+	require.Equal(t, "docker", sub.Origin)
+
+	_, err = client.CancelSubscription(ctx, testAuthToken, "testSubscriptionID", nil)
+	require.NoError(t, err)
+
+	_, err = client.UpdatePricingComponents(ctx, testAuthToken, "testSubscriptionID", &model.SubscriptionPricingComponentsUpdateRequest{
+		PricingComponents: model.PricingComponents{{Name: "Nodes", Value: 10}},
+	})
+	require.NoError(t, err)
+
+	_, err = client.RenewSubscription(ctx, testAuthToken, "testSubscriptionID", &model.SubscriptionRenewalRequest{
+		CouponCodes: []string{"RENEW10"},
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, []string{
+		"GET /api/billing/v4/subscriptions/testSubscriptionID ",
+		"POST /api/billing/v4/subscriptions/testSubscriptionID/cancel {}",
+		`PUT /api/billing/v4/subscriptions/testSubscriptionID/pricing-components {"pricing_components":[{"name":"Nodes","value":10}]}`,
+		`POST /api/billing/v4/subscriptions/testSubscriptionID/renew {"coupon_codes":["RENEW10"]}`,
+	}, requests)
+}
+
+func TestClient_SubscriptionLifecycleValidation(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	ctx := context.Background()
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		t.Fatalf("unexpected request %s %s", r.Method, r.URL)
+	})
+
+	_, err := client.GetSubscription(ctx, testAuthToken, "")
+	var verrs validation.Errors
+	require.True(t, errors.As(err, &verrs))
+	require.Equal(t, "subscription_id", verrs[0].FieldName)
+
+	_, err = client.UpdatePricingComponents(ctx, testAuthToken, "testSubscriptionID", &model.SubscriptionPricingComponentsUpdateRequest{
+		PricingComponents: model.PricingComponents{{Name: "", Value: -1}},
+	})
+	require.True(t, errors.As(err, &verrs))
+	require.Len(t, verrs, 2)
+	require.Equal(t, "pricing_component[0]/name", verrs[0].FieldName)
+	require.Equal(t, "pricing_component[0]/value", verrs[1].FieldName)
+
+	_, err = client.UpdatePricingComponents(ctx, testAuthToken, "testSubscriptionID", nil)
+	require.True(t, errors.As(err, &verrs))
+	require.Equal(t, "pricing_components", verrs[0].FieldName)
+
+	_, err = client.RenewSubscription(ctx, testAuthToken, "", &model.SubscriptionRenewalRequest{CouponCodes: []string{""}})
+	require.True(t, errors.As(err, &verrs))
+	require.Len(t, verrs, 2)
+
+	// ids are a single path segment
+	for _, id := range []string{"../../../v2/users/testUser", "sub/cancel", ".."} {
+		_, err = client.GetSubscription(ctx, testAuthToken, id)
+		require.True(t, errors.As(err, &verrs))
+		require.Equal(t, "subscription_id", verrs[0].FieldName)
+
+		_, err = client.CancelSubscription(ctx, testAuthToken, id, &model.SubscriptionCancellationRequest{})
+		require.True(t, errors.As(err, &verrs))
+
+		_, err = client.DownloadLicenseFromHub(ctx, testAuthToken, id)
+		require.True(t, errors.As(err, &verrs))
+	}
 }
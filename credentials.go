@@ -0,0 +1,252 @@
+package licensing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/docker/licensing/lib/errors"
+	"github.com/docker/licensing/lib/go-clientlib"
+	"github.com/docker/licensing/model"
+)
+
+// Credentials are exchanged for the hub JWT used to authenticate requests, see Client.Login and jwt.NewContext
+type Credentials interface {
+	Login(ctx context.Context, clnt Client) (authToken string, err error)
+}
+
+// PasswordCredentials log in with a hub username and password. If the account requires two factor
+// authentication, the code returned by TwoFactorCode is submitted to complete the login. Without TwoFactorCode,
+// the login fails with a *model.LoginError for which TwoFactorRequired is true.
+type PasswordCredentials struct {
+	Username string
+	Password string
+
+	TwoFactorCode func(ctx context.Context) (string, error)
+}
+
+// Login implements Credentials
+func (p *PasswordCredentials) Login(ctx context.Context, clnt Client) (string, error) {
+	token, err := clnt.LoginViaAuth(ctx, p.Username, p.Password)
+	if err == nil {
+		return token, nil
+	}
+
+	var loginErr *model.LoginError
+	if p.TwoFactorCode == nil || !errors.As(err, &loginErr) || !loginErr.TwoFactorRequired() {
+		return "", err
+	}
+
+	code, err := p.TwoFactorCode(ctx)
+	if err != nil {
+		return "", errors.Wrap(err, errors.Fields{
+			"username": p.Username,
+		})
+	}
+
+	token, err = clnt.LoginWith2FA(ctx, loginErr.Raw.Login2FAToken, code)
+	if err != nil {
+		return "", errors.Wrap(err, errors.Fields{
+			"username": p.Username,
+		})
+	}
+
+	return token, nil
+}
+
+// AccessTokenCredentials log in with a hub username and personal access token. Access tokens are not subject
+// to two factor authentication.
+type AccessTokenCredentials struct {
+	Username string
+	Token    string
+}
+
+// Login implements Credentials
+func (a *AccessTokenCredentials) Login(ctx context.Context, clnt Client) (string, error) {
+	return clnt.LoginViaAuth(ctx, a.Username, a.Token)
+}
+
+const (
+	deviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+	// defaultDevicePollInterval is used if the authorization server does not specify one, as per RFC 8628
+	defaultDevicePollInterval = 5 * time.Second
+	// deviceSlowDownIncrement is added to the poll interval on each slow_down response, as per RFC 8628
+	deviceSlowDownIncrement = 5 * time.Second
+)
+
+// DeviceFlowCredentials log in through the OAuth2 device authorization grant (RFC 8628), for clients that cannot
+// prompt for a password, such as CLIs logging in to SSO accounts. The user is prompted to visit a verification url
+// and enter a code, while the token endpoint is polled until the user has approved or denied the login. The
+// resulting access token is used as the hub JWT.
+type DeviceFlowCredentials struct {
+	DeviceAuthorizationURL string
+	TokenURL               string
+	ClientID               string
+	Scopes                 []string
+	Audience               string
+
+	// Prompt is called with the verification url and user code to present to the user
+	Prompt func(ctx context.Context, auth *model.DeviceAuthorization) error
+
+	// HTTPClient is used to call the authorization server, defaults to http.DefaultClient
+	HTTPClient *http.Client
+
+	// PollInterval, if set, overrides the poll interval returned by the authorization server
+	PollInterval time.Duration
+}
+
+// Login implements Credentials
+func (d *DeviceFlowCredentials) Login(ctx context.Context, clnt Client) (string, error) {
+	auth, err := d.authorize(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if d.Prompt != nil {
+		if err := d.Prompt(ctx, auth); err != nil {
+			return "", err
+		}
+	}
+
+	interval := d.PollInterval
+	if interval == 0 {
+		interval = time.Duration(auth.Interval) * time.Second
+	}
+	if interval == 0 {
+		interval = defaultDevicePollInterval
+	}
+
+	if auth.ExpiresIn > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(auth.ExpiresIn)*time.Second)
+		defer cancel()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return "", errors.Wrap(ctx.Err(), errors.Fields{
+				"client_id": d.ClientID,
+			})
+		case <-time.After(interval):
+		}
+
+		token, err := d.token(ctx, auth)
+		if err == nil {
+			return token.AccessToken, nil
+		}
+
+		var oauthErr *model.OAuthError
+		if !errors.As(err, &oauthErr) {
+			return "", err
+		}
+
+		switch oauthErr.Code {
+		case model.OAuthAuthorizationPending:
+		case model.OAuthSlowDown:
+			interval += deviceSlowDownIncrement
+		default:
+			return "", err
+		}
+	}
+}
+
+// authorize requests a device and user code
+func (d *DeviceFlowCredentials) authorize(ctx context.Context) (*model.DeviceAuthorization, error) {
+	form := url.Values{}
+	form.Set("client_id", d.ClientID)
+	if len(d.Scopes) > 0 {
+		form.Set("scope", strings.Join(d.Scopes, " "))
+	}
+	if d.Audience != "" {
+		form.Set("audience", d.Audience)
+	}
+
+	auth := new(model.DeviceAuthorization)
+	_, _, err := clientlib.Do(ctx, "POST", d.DeviceAuthorizationURL, d.requestOpts(clientlib.SendForm(form), clientlib.RecvJSON(auth))...)
+	if err != nil {
+		return nil, err
+	}
+
+	if auth.DeviceCode == "" {
+		return nil, errors.Wrapf(fmt.Errorf("missing device code"), errors.Fields{
+			"url": d.DeviceAuthorizationURL,
+		}, "invalid device authorization response")
+	}
+
+	return auth, nil
+}
+
+// token polls the token endpoint for the access token of an approved device authorization
+func (d *DeviceFlowCredentials) token(ctx context.Context, auth *model.DeviceAuthorization) (*model.OAuthToken, error) {
+	form := url.Values{}
+	form.Set("grant_type", deviceCodeGrantType)
+	form.Set("device_code", auth.DeviceCode)
+	form.Set("client_id", d.ClientID)
+
+	token := new(model.OAuthToken)
+	_, _, err := clientlib.Do(ctx, "POST", d.TokenURL, d.requestOpts(clientlib.SendForm(form), clientlib.RecvJSON(token))...)
+	if err != nil {
+		return nil, err
+	}
+
+	if token.AccessToken == "" {
+		return nil, errors.Wrapf(fmt.Errorf("missing access token"), errors.Fields{
+			"url": d.TokenURL,
+		}, "invalid token response")
+	}
+
+	return token, nil
+}
+
+func (d *DeviceFlowCredentials) requestOpts(opts ...clientlib.RequestOption) []clientlib.RequestOption {
+	return append([]clientlib.RequestOption{
+		func(req *clientlib.Request) {
+			if d.HTTPClient != nil {
+				req.Client = d.HTTPClient
+			}
+			req.ErrorBodyMaxLength = errBodyMaxLength
+		},
+		oauthErrorCheckOpt,
+	}, opts...)
+}
+
+// oauthErrorCheckOpt works similarly to `clientlib.DefaultErrorCheck`, except it parses the OAuth2 error response
+func oauthErrorCheckOpt(r *clientlib.Request) {
+	r.ErrorCheck = func(r *clientlib.Request, doErr error, res *http.Response) error {
+		if doErr != nil {
+			return transportError(r, doErr)
+		}
+		status := res.StatusCode
+		if status >= 200 && status < 300 {
+			return nil
+		}
+
+		defer res.Body.Close()
+
+		message := fmt.Sprintf("%s %s returned %d", r.Method, r.URL.String(), status)
+		oauthErr := &model.OAuthError{
+			HTTPError: errors.NewHTTPError(status, message).
+				With(r.ErrorFields()),
+		}
+
+		var raw struct {
+			Code        string `json:"error"`
+			Description string `json:"error_description"`
+		}
+		body, _ := ioutil.ReadAll(io.LimitReader(res.Body, r.ErrorBodyMaxLength))
+		if err := json.Unmarshal(body, &raw); err == nil {
+			oauthErr.Code = raw.Code
+			oauthErr.Description = raw.Description
+		}
+
+		return oauthErr
+	}
+}
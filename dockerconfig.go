@@ -0,0 +1,273 @@
+package licensing
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/licensing/lib/errors"
+)
+
+const (
+	// DefaultHubServerAddress is the server address hub credentials are stored under by the docker cli
+	DefaultHubServerAddress = "https://index.docker.io/v1/"
+
+	// credential helper binaries are named with this prefix followed by the helper name
+	credentialHelperPrefix = "docker-credential-"
+
+	// credential helpers return this message if no credentials are stored for the server
+	credentialsNotFoundMessage = "credentials not found in native keychain"
+
+	// stored credentials with this username hold an identity token rather than a password
+	identityTokenUsername = "<token>"
+)
+
+// StoredCredentials holds credentials read from the docker credential store. The json encoding matches the
+// credential helper protocol.
+type StoredCredentials struct {
+	ServerURL string `json:"ServerURL"`
+	Username  string `json:"Username"`
+	Secret    string `json:"Secret"`
+}
+
+// String redacts the secret
+func (s StoredCredentials) String() string {
+	return fmt.Sprintf("{ServerURL:%s Username:%s Secret:%s}", s.ServerURL, s.Username, errors.RedactedText)
+}
+
+// GoString redacts the secret
+func (s StoredCredentials) GoString() string {
+	return fmt.Sprintf("licensing.StoredCredentials{ServerURL:%q, Username:%q, Secret:%q}", s.ServerURL, s.Username, errors.RedactedText)
+}
+
+// IsIdentityToken returns true if Secret is an identity token rather than a password
+func (s StoredCredentials) IsIdentityToken() bool {
+	return s.Username == identityTokenUsername
+}
+
+// DockerConfigCredentials log in with the hub credentials stored by the docker cli, either in the docker config
+// file or through a credential helper (docker-credential-*) configured in it. Stored passwords or access tokens
+// are used with LoginViaAuth. Stored identity tokens, as saved by OAuth logins such as Docker Desktop's, are
+// registry refresh tokens rather than hub JWTs: Login fails with ErrIdentityTokenNotSupported for them.
+type DockerConfigCredentials struct {
+	// ConfigFile is the path of the docker config file. Defaults to config.json in $DOCKER_CONFIG, or in
+	// ~/.docker if unset.
+	ConfigFile string
+
+	// ServerAddress is the address credentials are stored under, defaults to DefaultHubServerAddress
+	ServerAddress string
+
+	// TwoFactorCode is used as with PasswordCredentials
+	TwoFactorCode func(ctx context.Context) (string, error)
+}
+
+// dockerConfigFile holds the credential related fields of a docker config file
+type dockerConfigFile struct {
+	Auths       map[string]dockerConfigAuth `json:"auths"`
+	CredsStore  string                      `json:"credsStore,omitempty"`
+	CredHelpers map[string]string           `json:"credHelpers,omitempty"`
+}
+
+type dockerConfigAuth struct {
+	Auth          string `json:"auth,omitempty"`
+	Username      string `json:"username,omitempty"`
+	Password      string `json:"password,omitempty"`
+	IdentityToken string `json:"identitytoken,omitempty"`
+}
+
+// Login implements Credentials
+func (d *DockerConfigCredentials) Login(ctx context.Context, clnt Client) (string, error) {
+	creds, err := d.Lookup(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if creds.IsIdentityToken() {
+		return "", errors.Wrapf(ErrIdentityTokenNotSupported, errors.Fields{
+			"server_url": creds.ServerURL,
+		}, "log in with a password or access token instead")
+	}
+
+	return (&PasswordCredentials{
+		Username:      creds.Username,
+		Password:      creds.Secret,
+		TwoFactorCode: d.TwoFactorCode,
+	}).Login(ctx, clnt)
+}
+
+// Lookup returns the stored credentials. Credentials are looked up in the credential helper configured for the
+// server, then in the default credential store, and then in the config file itself.
+func (d *DockerConfigCredentials) Lookup(ctx context.Context) (*StoredCredentials, error) {
+	configFile := d.configFile()
+	serverAddress := d.serverAddress()
+	fields := errors.Fields{
+		"config_file":    configFile,
+		"server_address": serverAddress,
+	}
+
+	bits, err := ioutil.ReadFile(configFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.Wrapf(ErrCredentialsNotFound, fields, "no docker config file")
+		}
+		return nil, errors.Wrap(err, fields)
+	}
+
+	var config dockerConfigFile
+	if err := json.Unmarshal(bits, &config); err != nil {
+		return nil, errors.Wrapf(err, fields, "invalid docker config file")
+	}
+
+	if helper := findServerEntry(config.CredHelpers, serverAddress); helper != "" {
+		return runCredentialHelper(ctx, helper, serverAddress)
+	}
+
+	if config.CredsStore != "" {
+		return runCredentialHelper(ctx, config.CredsStore, serverAddress)
+	}
+
+	for key, auth := range config.Auths {
+		if !sameServer(key, serverAddress) {
+			continue
+		}
+
+		creds, err := auth.credentials(key)
+		if err != nil {
+			return nil, errors.Wrap(err, fields)
+		}
+		if creds != nil {
+			return creds, nil
+		}
+	}
+
+	return nil, errors.Wrap(ErrCredentialsNotFound, fields)
+}
+
+func (d *DockerConfigCredentials) configFile() string {
+	if d.ConfigFile != "" {
+		return d.ConfigFile
+	}
+
+	dir := os.Getenv("DOCKER_CONFIG")
+	if dir == "" {
+		home, _ := os.UserHomeDir()
+		dir = filepath.Join(home, ".docker")
+	}
+	return filepath.Join(dir, "config.json")
+}
+
+func (d *DockerConfigCredentials) serverAddress() string {
+	if d.ServerAddress != "" {
+		return d.ServerAddress
+	}
+	return DefaultHubServerAddress
+}
+
+// credentials decodes the credentials of an auths entry, returning nil if it holds none
+func (a dockerConfigAuth) credentials(serverURL string) (*StoredCredentials, error) {
+	creds := &StoredCredentials{
+		ServerURL: serverURL,
+		Username:  a.Username,
+		Secret:    a.Password,
+	}
+
+	if a.Auth != "" {
+		decoded, err := base64.StdEncoding.DecodeString(a.Auth)
+		if err != nil {
+			return nil, fmt.Errorf("invalid auth for %s: %s", serverURL, err)
+		}
+
+		parts := strings.SplitN(string(decoded), ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid auth for %s: expected username:password", serverURL)
+		}
+		creds.Username, creds.Secret = parts[0], parts[1]
+	}
+
+	if a.IdentityToken != "" {
+		creds.Username, creds.Secret = identityTokenUsername, a.IdentityToken
+	}
+
+	if creds.Secret == "" {
+		return nil, nil
+	}
+	return creds, nil
+}
+
+// runCredentialHelper gets the credentials stored for serverAddress from the named credential helper
+func runCredentialHelper(ctx context.Context, helper, serverAddress string) (*StoredCredentials, error) {
+	name := credentialHelperPrefix + helper
+	fields := errors.Fields{
+		"helper":         name,
+		"server_address": serverAddress,
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, "get")
+	cmd.Stdin = strings.NewReader(serverAddress)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		// helpers report errors on stdout
+		out := strings.TrimSpace(stdout.String())
+		if out == credentialsNotFoundMessage {
+			return nil, errors.Wrap(ErrCredentialsNotFound, fields)
+		}
+		if out == "" {
+			out = strings.TrimSpace(stderr.String())
+		}
+		return nil, errors.Wrapf(err, fields, "credential helper %s failed: %s", name, out)
+	}
+
+	creds := new(StoredCredentials)
+	if err := json.Unmarshal(stdout.Bytes(), creds); err != nil {
+		return nil, errors.Wrapf(err, fields, "invalid credential helper %s output", name)
+	}
+
+	if creds.Secret == "" {
+		return nil, errors.Wrap(ErrCredentialsNotFound, fields)
+	}
+	return creds, nil
+}
+
+// findServerEntry returns the value of the entry of m for the given server, or an empty string if there is none
+func findServerEntry(m map[string]string, serverAddress string) string {
+	for key, value := range m {
+		if sameServer(key, serverAddress) {
+			return value
+		}
+	}
+	return ""
+}
+
+// sameServer returns true if both addresses refer to the same registry host. Credentials are stored under
+// addresses with or without a scheme and path, and docker.io aliases the hub registry.
+func sameServer(a, b string) bool {
+	return serverHost(a) == serverHost(b)
+}
+
+func serverHost(address string) string {
+	if !strings.Contains(address, "://") {
+		address = "https://" + address
+	}
+
+	host := address
+	if u, err := url.Parse(address); err == nil {
+		host = u.Host
+	}
+
+	switch host {
+	case "docker.io", "registry-1.docker.io":
+		return "index.docker.io"
+	}
+	return host
+}
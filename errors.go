@@ -0,0 +1,273 @@
+package licensing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/docker/licensing/lib/errors"
+	"github.com/docker/licensing/lib/go-clientlib"
+	"github.com/docker/licensing/model"
+)
+
+var (
+	// ErrUnrecognizedSigningKey returned when a license is signed by a key that is not among the client's public keys
+	ErrUnrecognizedSigningKey = fmt.Errorf("unrecognized signing key")
+	// ErrInvalidSignature returned when a license signature is malformed or fails verification
+	ErrInvalidSignature = fmt.Errorf("invalid license signature")
+	// ErrInvalidLicenseToken returned when a license token does not match its private key
+	ErrInvalidLicenseToken = fmt.Errorf("invalid license token")
+
+	// ErrUnauthorized returned when the request credentials were missing or rejected
+	ErrUnauthorized = fmt.Errorf("unauthorized")
+	// ErrForbidden returned when the authenticated user may not perform the request
+	ErrForbidden = fmt.Errorf("forbidden")
+	// ErrTokenExpired returned when the request was rejected because the auth token has expired
+	ErrTokenExpired = fmt.Errorf("auth token expired")
+	// ErrMissingAuthToken returned, along with ErrUnauthorized, when an authenticated request is made without
+	// an auth token or TokenSource
+	ErrMissingAuthToken = fmt.Errorf("missing auth token")
+	// ErrCredentialsNotFound returned when no credentials are stored for the server in the docker config file or
+	// credential store
+	ErrCredentialsNotFound = fmt.Errorf("credentials not found")
+	// ErrIdentityTokenNotSupported returned when the stored credentials are an identity token: a registry OAuth
+	// refresh token, which cannot be used as, nor exchanged for, a hub JWT
+	ErrIdentityTokenNotSupported = fmt.Errorf("identity tokens not supported")
+
+	// ErrNotFound returned when the requested resource does not exist
+	ErrNotFound = fmt.Errorf("not found")
+	// ErrSubscriptionNotFound returned when the requested subscription does not exist
+	ErrSubscriptionNotFound = fmt.Errorf("subscription not found")
+	// ErrUserNotFound returned when the requested hub user does not exist
+	ErrUserNotFound = fmt.Errorf("user not found")
+
+	// ErrConflict returned when the request conflicts with the current state of a resource
+	ErrConflict = fmt.Errorf("conflict")
+	// ErrTrialIneligible returned when a trial subscription was requested for a user who is not eligible for one
+	ErrTrialIneligible = fmt.Errorf("not eligible for a trial")
+)
+
+// VerificationError is returned when an issued license fails verification. Reason holds one of
+// ErrUnrecognizedSigningKey, ErrInvalidSignature or ErrInvalidLicenseToken.
+type VerificationError struct {
+	KeyID  string
+	Reason error
+	Err    error
+}
+
+func (e *VerificationError) Error() string {
+	if e.Err == nil {
+		return e.Reason.Error()
+	}
+	return fmt.Sprintf("%s: %s", e.Reason, e.Err)
+}
+
+// Is reports whether target is the verification failure reason.
+func (e *VerificationError) Is(target error) bool {
+	return target == e.Reason
+}
+
+// Unwrap returns the underlying verification error, if any.
+func (e *VerificationError) Unwrap() error {
+	return e.Err
+}
+
+// AuthError is returned when a request is rejected with a 401 or 403 status.
+type AuthError struct {
+	*errors.HTTPError
+	// Expired is true if the service reported the auth token as expired
+	Expired bool
+}
+
+// Is matches ErrUnauthorized, ErrForbidden and ErrTokenExpired according to the response.
+func (e *AuthError) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.Status == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.Status == http.StatusForbidden
+	case ErrTokenExpired:
+		return e.Expired
+	}
+	return false
+}
+
+// Unwrap returns the underlying http error.
+func (e *AuthError) Unwrap() error {
+	return e.HTTPError
+}
+
+// NotFoundError is returned when a request is rejected with a 404 status. Err holds ErrNotFound,
+// or a more specific sentinel such as ErrSubscriptionNotFound.
+type NotFoundError struct {
+	*errors.HTTPError
+	Err error
+}
+
+// Is matches ErrNotFound as well as the resource specific sentinel.
+func (e *NotFoundError) Is(target error) bool {
+	return target == ErrNotFound || target == e.Err
+}
+
+// Unwrap returns the underlying http error.
+func (e *NotFoundError) Unwrap() error {
+	return e.HTTPError
+}
+
+// ConflictError is returned when a request is rejected with a 409 status. Err holds ErrConflict,
+// or a more specific sentinel such as ErrTrialIneligible.
+type ConflictError struct {
+	*errors.HTTPError
+	Err error
+}
+
+// Is matches ErrConflict as well as the request specific sentinel.
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrConflict || target == e.Err
+}
+
+// Unwrap returns the underlying http error.
+func (e *ConflictError) Unwrap() error {
+	return e.HTTPError
+}
+
+// TransportError is returned when a request could not be completed, for example because of a connection
+// failure, and no response was received.
+type TransportError struct {
+	Method string
+	URL    string
+	Err    error
+}
+
+func (e *TransportError) Error() string {
+	return fmt.Sprintf("%s %s failed: %s", e.Method, e.URL, e.Err)
+}
+
+// Unwrap returns the underlying transport error.
+func (e *TransportError) Unwrap() error {
+	return e.Err
+}
+
+const (
+	// error response bodies are read up to this many bytes
+	errBodyMaxLength = 16 << 10
+	// unstructured error response bodies are summarized up to this many bytes
+	errSummaryMaxLength = 256
+)
+
+// errorCheck works like `clientlib.DefaultErrorCheck`, except it decodes the error body into a
+// model.APIError wrapped by the returned http error, and translates transport failures and well
+// known statuses into the typed errors above
+func errorCheck(r *clientlib.Request, doErr error, res *http.Response) error {
+	if doErr != nil {
+		return transportError(r, doErr)
+	}
+	status := res.StatusCode
+	if status >= 200 && status < 300 {
+		return nil
+	}
+
+	defer res.Body.Close()
+
+	body, _ := ioutil.ReadAll(io.LimitReader(res.Body, r.ErrorBodyMaxLength))
+	apiErr := decodeAPIError(res.Header.Get("Content-Type"), body)
+	detail := apiErr.Summary()
+
+	herr := errors.NewHTTPErrorf(status, "%s %s returned %d : %w", r.Method, r.URL.String(), status, apiErr).
+		With(r.ErrorFields()).
+		With(errors.Fields{
+			"http_status": status,
+			"detail":      detail,
+		})
+	return classifyHTTPError(herr)
+}
+
+// decodeAPIError decodes json error bodies, including RFC 7807 `application/problem+json`, falling
+// back to the raw (truncated) body as the error detail
+func decodeAPIError(contentType string, body []byte) *model.APIError {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	isJSON := mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+
+	body = bytes.TrimSpace(body)
+	if isJSON || bytes.HasPrefix(body, []byte("{")) {
+		apiErr := new(model.APIError)
+		if err := json.Unmarshal(body, apiErr); err == nil {
+			return apiErr
+		}
+	}
+
+	if len(body) > errSummaryMaxLength {
+		body = body[:errSummaryMaxLength]
+	}
+	return &model.APIError{
+		Detail: clientlib.DefaultErrorSummary(body),
+	}
+}
+
+func transportError(r *clientlib.Request, doErr error) error {
+	return errors.Wrap(&TransportError{
+		Method: r.Method,
+		URL:    r.URL.String(),
+		Err:    doErr,
+	}, r.ErrorFields())
+}
+
+// classifyHTTPError returns a typed error for the status of herr, or herr itself if there is none
+func classifyHTTPError(herr *errors.HTTPError) error {
+	switch herr.Status {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		detail, _ := herr.Fields["detail"].(string)
+		return &AuthError{
+			HTTPError: herr,
+			Expired:   strings.Contains(strings.ToLower(detail), "expired"),
+		}
+	case http.StatusNotFound:
+		return &NotFoundError{
+			HTTPError: herr,
+			Err:       ErrNotFound,
+		}
+	case http.StatusConflict:
+		return &ConflictError{
+			HTTPError: herr,
+			Err:       ErrConflict,
+		}
+	}
+	return herr
+}
+
+// notFoundErrorOpt sets the sentinel matched by a NotFoundError returned from the request
+func notFoundErrorOpt(sentinel error) clientlib.RequestOption {
+	return errorCheckOpt(func(err error) {
+		if nf, ok := err.(*NotFoundError); ok {
+			nf.Err = sentinel
+		}
+	})
+}
+
+// conflictErrorOpt sets the sentinel matched by a ConflictError returned from the request
+func conflictErrorOpt(sentinel error) clientlib.RequestOption {
+	return errorCheckOpt(func(err error) {
+		if ce, ok := err.(*ConflictError); ok {
+			ce.Err = sentinel
+		}
+	})
+}
+
+// errorCheckOpt calls update with every error returned by the request's error check
+func errorCheckOpt(update func(err error)) clientlib.RequestOption {
+	return func(r *clientlib.Request) {
+		check := r.ErrorCheck
+		r.ErrorCheck = func(r *clientlib.Request, doErr error, res *http.Response) error {
+			err := check(r, doErr, res)
+			if err != nil {
+				update(err)
+			}
+			return err
+		}
+	}
+}
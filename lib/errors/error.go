@@ -5,7 +5,10 @@ package errors
 import (
 	"bytes"
 	"encoding/json"
+	stderrors "errors"
 	"fmt"
+	"sort"
+	"strings"
 )
 
 // New returns a base error that captures the call stack.
@@ -13,6 +16,54 @@ func New(text string) error {
 	return NewBase(1, text)
 }
 
+// Errorf returns a base error that captures the call stack, formatted
+// according to fmt.Errorf. Any %w operands are retained, and can be
+// matched by the standard errors.Is & errors.As.
+func Errorf(format string, args ...interface{}) error {
+	b := NewBase(1, "")
+	b.Text, b.wrapped = formatWrapped(format, args...)
+	return b
+}
+
+// Join returns a base error that captures the call stack and wraps the
+// given errors, discarding any nils. Like the standard errors.Join, it
+// returns nil if every error is nil.
+func Join(errs ...error) error {
+	var wrapped []error
+	var texts []string
+	for _, err := range errs {
+		if err != nil {
+			wrapped = append(wrapped, err)
+			texts = append(texts, err.Error())
+		}
+	}
+	if len(wrapped) == 0 {
+		return nil
+	}
+
+	b := NewBase(1, strings.Join(texts, "\n"))
+	b.wrapped = wrapped
+	return b
+}
+
+// Is reports whether any error in err's chain matches target.
+// It is the standard library errors.Is, provided here for convenience.
+func Is(err, target error) bool {
+	return stderrors.Is(err, target)
+}
+
+// As finds the first error in err's chain that matches target.
+// It is the standard library errors.As, provided here for convenience.
+func As(err error, target interface{}) bool {
+	return stderrors.As(err, target)
+}
+
+// Unwrap returns the result of calling the Unwrap method on err, if any.
+// It is the standard library errors.Unwrap, provided here for convenience.
+func Unwrap(err error) error {
+	return stderrors.Unwrap(err)
+}
+
 // Base is an error type that supports capturing the call stack at creation
 // time, and storing separate text & data to allow structured logging.
 // While it could be used directly, it may make more sense as an
@@ -21,9 +72,13 @@ type Base struct {
 	Text      string
 	Fields    Fields
 	CallStack CallStack
+
+	// errors wrapped via Errorf's %w verbs or Join
+	wrapped []error
 }
 
-// Fields holds the annotations for an error.
+// Fields holds the annotations for an error. Values of sensitive
+// fields are redacted when the error is rendered, see Redact.
 type Fields map[string]interface{}
 
 // NewBase creates a new Base, capturing a call trace starting
@@ -73,7 +128,7 @@ func (e *Base) MarshalJSON() ([]byte, error) {
 		m["text"] = e.Text
 	}
 	if len(e.Fields) > 0 {
-		m["fields"] = e.Fields
+		m["fields"] = e.Fields.Redact()
 	}
 	return json.Marshal(m)
 }
@@ -83,6 +138,29 @@ func (e *Base) Stack() CallStack {
 	return e.CallStack
 }
 
+// base gives access to the Base of any error type embedding one.
+func (e *Base) base() *Base {
+	return e
+}
+
+// Unwrap returns the errors wrapped by this Error via Errorf or Join, if any.
+func (e *Base) Unwrap() []error {
+	return e.wrapped
+}
+
+// formatWrapped formats according to fmt.Errorf, returning the text
+// along with any errors wrapped by %w verbs.
+func formatWrapped(format string, args ...interface{}) (string, []error) {
+	err := fmt.Errorf(format, args...)
+	switch u := err.(type) {
+	case interface{ Unwrap() error }:
+		return err.Error(), []error{u.Unwrap()}
+	case interface{ Unwrap() []error }:
+		return err.Error(), u.Unwrap()
+	}
+	return err.Error(), nil
+}
+
 func combineFields(f1 Fields, f2 Fields) Fields {
 	data := make(Fields, len(f1)+len(f2))
 	for k, v := range f1 {
@@ -101,11 +179,19 @@ func textAndFields(text string, fields Fields) string {
 		buf.WriteString(text)
 	}
 
-	for k, v := range fields {
+	// fields are written in key order, so that the same error always has the same text
+	redacted := fields.Redact()
+	keys := make([]string, 0, len(redacted))
+	for k := range redacted {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
 		buf.WriteByte(' ')
 		buf.WriteString(k)
 		buf.WriteByte('=')
-		fmt.Fprintf(buf, "%+v", v)
+		fmt.Fprintf(buf, "%+v", redacted[k])
 	}
 
 	return string(buf.Bytes())
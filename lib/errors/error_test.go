@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"path"
 	"runtime"
@@ -249,6 +251,75 @@ func TestNewHTTPError(t *testing.T) {
 	require.Equal(t, http.StatusTeapot, status)
 }
 
+func TestStdUnwrap(t *testing.T) {
+	t.Parallel()
+
+	terr := top2()
+
+	require.True(t, errors.Is(terr, terr))
+	require.Equal(t, "something not found", Unwrap(Unwrap(terr)).(*HTTPError).Text)
+
+	var herr *HTTPError
+	require.True(t, errors.As(terr, &herr))
+	require.Equal(t, http.StatusNotFound, herr.Status)
+
+	var werr *Wrapped
+	require.True(t, errors.As(fmt.Errorf("outer: %w", terr), &werr))
+	require.Equal(t, terr, werr)
+
+	// Cause keeps looking through Wrapped layers only
+	_, wraps, cause := Cause(terr)
+	require.Len(t, wraps, 2)
+	require.Equal(t, herr, cause)
+}
+
+func TestWrapfVerbW(t *testing.T) {
+	t.Parallel()
+
+	err := Wrapf(errors.New("cause"), nil, "reading: %w", io.EOF)
+	require.Equal(t, "reading: EOF: cause", err.Error())
+	require.True(t, errors.Is(err, io.EOF))
+	require.Equal(t, "cause", errors.Unwrap(err).Error())
+}
+
+func TestErrorf(t *testing.T) {
+	t.Parallel()
+
+	err := Errorf("failed %d: %w", 1, io.ErrUnexpectedEOF)
+	require.Equal(t, "failed 1: unexpected EOF", err.Error())
+	require.True(t, Is(err, io.ErrUnexpectedEOF))
+	require.Equal(t, "errors.TestErrorf", path.Base(err.(*Base).Location().Func))
+
+	herr := NewHTTPErrorf(http.StatusBadGateway, "upstream: %w", io.EOF)
+	require.True(t, errors.Is(herr, io.EOF))
+	status, ok := HTTPStatus(Wrap(herr, nil))
+	require.True(t, ok)
+	require.Equal(t, http.StatusBadGateway, status)
+}
+
+func TestJoin(t *testing.T) {
+	t.Parallel()
+
+	require.Nil(t, Join(nil, nil))
+
+	nf := NotFound(nil, "missing")
+	err := Join(io.EOF, nil, Wrap(nf, Fields{"id": 1}))
+	require.True(t, errors.Is(err, io.EOF))
+
+	var herr *HTTPError
+	require.True(t, As(err, &herr))
+	require.Equal(t, nf, herr)
+	require.Len(t, err.(*Base).Unwrap(), 2)
+
+	// JSON output keeps the Base shape
+	bits, err := json.Marshal(err)
+	require.NoError(t, err)
+	var m map[string]interface{}
+	require.NoError(t, json.Unmarshal(bits, &m))
+	require.Equal(t, "EOF\nmissing id=1: missing", m["text"])
+	require.Equal(t, "errors.TestJoin", path.Base(m["func"].(string)))
+}
+
 func BenchmarkCallstack(b *testing.B) {
 	for n := 0; n < b.N; n++ {
 		var callers [2]uintptr
@@ -272,3 +343,13 @@ func BenchmarkCallstack(b *testing.B) {
 		}
 	}
 }
+
+func TestFieldsOrder(t *testing.T) {
+	t.Parallel()
+
+	fields := Fields{"c": 3, "a": 1, "b": 2, "d": 4, "e": 5}
+	err := Wrapf(NewBase(0, "base"), fields, "wrapped")
+	for i := 0; i < 20; i++ {
+		require.Equal(t, "wrapped a=1 b=2 c=3 d=4 e=5: base", err.Error())
+	}
+}
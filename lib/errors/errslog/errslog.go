@@ -0,0 +1,68 @@
+// Package errslog adapts errors to log/slog attributes, exposing the
+// structured information carried by lib/errors chains.
+package errslog
+
+import (
+	"log/slog"
+	"sort"
+
+	"github.com/docker/licensing/lib/errors"
+)
+
+// Key is the attribute key used by Attr.
+const Key = "error"
+
+// Attr returns a group attribute describing err, keyed by Key.
+func Attr(err error) slog.Attr {
+	return slog.Attr{Key: Key, Value: Value(err)}
+}
+
+// Value returns a group value holding the message, merged fields, cause,
+// http status and trimmed call stack of err. Fields, cause, status and
+// stack are omitted when empty or redundant.
+func Value(err error) slog.Value {
+	s := errors.Summarize(err)
+	if s == nil {
+		return slog.StringValue("<nil>")
+	}
+
+	attrs := []slog.Attr{slog.String("msg", s.Message)}
+	if len(s.Fields) > 0 {
+		keys := make([]string, 0, len(s.Fields))
+		for k := range s.Fields {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		fields := make([]slog.Attr, len(keys))
+		for i, k := range keys {
+			fields[i] = slog.Any(k, s.Fields[k])
+		}
+		attrs = append(attrs, slog.Attr{Key: "fields", Value: slog.GroupValue(fields...)})
+	}
+	if s.Cause != s.Message {
+		attrs = append(attrs, slog.String("cause", s.Cause))
+	}
+	if s.HTTPStatus != 0 {
+		attrs = append(attrs, slog.Int("http_status", s.HTTPStatus))
+	}
+	if len(s.Stack) > 0 {
+		attrs = append(attrs, slog.Any("stack", s.Frames()))
+	}
+
+	return slog.GroupValue(attrs...)
+}
+
+// Valuer returns a slog.LogValuer for err, deferring the work of
+// summarizing it until the record is handled.
+func Valuer(err error) slog.LogValuer {
+	return valuer{err}
+}
+
+type valuer struct {
+	err error
+}
+
+func (v valuer) LogValue() slog.Value {
+	return Value(v.err)
+}
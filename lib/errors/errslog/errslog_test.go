@@ -0,0 +1,55 @@
+package errslog_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"github.com/docker/licensing/lib/errors"
+	"github.com/docker/licensing/lib/errors/errslog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttr(t *testing.T) {
+	t.Parallel()
+
+	buf := &bytes.Buffer{}
+	logger := slog.New(slog.NewJSONHandler(buf, nil))
+
+	cause := errors.NotFound(errors.Fields{"id": "sub1", "token": "s3cr3t"}, "subscription not found")
+	err := errors.Wrapf(cause, errors.Fields{"docker_id": "user1"}, "get failed")
+	logger.Error("request failed", errslog.Attr(err))
+
+	require.NotContains(t, buf.String(), "s3cr3t")
+
+	var record struct {
+		Error struct {
+			Msg        string                 `json:"msg"`
+			Fields     map[string]interface{} `json:"fields"`
+			Cause      string                 `json:"cause"`
+			HTTPStatus int                    `json:"http_status"`
+			Stack      []string               `json:"stack"`
+		} `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	require.Equal(t, err.Error(), record.Error.Msg)
+	require.Equal(t, cause.Error(), record.Error.Cause)
+	require.Equal(t, http.StatusNotFound, record.Error.HTTPStatus)
+	require.Equal(t, "sub1", record.Error.Fields["id"])
+	require.Equal(t, "user1", record.Error.Fields["docker_id"])
+	require.Equal(t, errors.RedactedText, record.Error.Fields["token"])
+	require.NotEmpty(t, record.Error.Stack)
+}
+
+func TestValuer(t *testing.T) {
+	t.Parallel()
+
+	buf := &bytes.Buffer{}
+	logger := slog.New(slog.NewTextHandler(buf, nil))
+
+	logger.Info("plain", "error", errslog.Valuer(errors.New("boom")))
+	require.Contains(t, buf.String(), "error.msg=boom")
+	require.NotContains(t, buf.String(), "error.cause")
+}
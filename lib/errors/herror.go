@@ -1,18 +1,21 @@
 package errors
 
-import "net/http"
+import (
+	stderrors "errors"
+	"net/http"
+)
 
 // HTTPStatus is a convenience checker for a (possibly wrapped) HTTPStatus
-// interface error. If err doesn't support the HTTPStatus interface, it will
-// default to either StatusOK or StatusInternalServerError as appropriate.
+// interface error. The first error in the chain supporting the interface
+// wins. If err doesn't support the HTTPStatus interface, it will default to
+// either StatusOK or StatusInternalServerError as appropriate.
 func HTTPStatus(err error) (status int, ok bool) {
 	type httperror interface {
 		HTTPStatus() int
 	}
 
-	_, _, cause := Cause(err)
-	he, ok := cause.(httperror)
-	if ok {
+	var he httperror
+	if stderrors.As(err, &he) {
 		return he.HTTPStatus(), true
 	}
 
@@ -58,6 +61,15 @@ func NewHTTPError(status int, text string) *HTTPError {
 	return newHTTPErrorWithDepth(status, text)
 }
 
+// NewHTTPErrorf constructs an error with the given http status, formatted
+// according to fmt.Errorf. Any %w operands are retained, and can be matched
+// by the standard errors.Is & errors.As.
+func NewHTTPErrorf(status int, format string, args ...interface{}) *HTTPError {
+	e := newHTTPErrorWithDepth(status, "")
+	e.Text, e.wrapped = formatWrapped(format, args...)
+	return e
+}
+
 // newHTTPErrorWithDepth constructs an error with the given http status and
 // depth
 func newHTTPErrorWithDepth(status int, text string) *HTTPError {
@@ -89,6 +101,12 @@ func PaymentRequired(fields Fields, text string) *HTTPError {
 	return newHTTPErrorWithDepth(http.StatusPaymentRequired, text).With(fields)
 }
 
+// Unauthorized is returned if a request lacks valid authentication
+// credentials.
+func Unauthorized(fields Fields, text string) *HTTPError {
+	return newHTTPErrorWithDepth(http.StatusUnauthorized, text).With(fields)
+}
+
 // Forbidden is returned if the requesting user does not have the required
 // permissions for a request.
 func Forbidden(fields Fields, text string) *HTTPError {
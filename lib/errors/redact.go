@@ -0,0 +1,92 @@
+package errors
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+)
+
+// RedactedText is rendered in place of sensitive values.
+const RedactedText = "[REDACTED]"
+
+// sensitiveFields holds the lower cased names of fields whose values are
+// always redacted when an error is rendered.
+var sensitiveFields = struct {
+	sync.RWMutex
+	names map[string]struct{}
+}{
+	names: map[string]struct{}{
+		"password":      {},
+		"token":         {},
+		"access_token":  {},
+		"refresh_token": {},
+		"private_key":   {},
+		"secret":        {},
+		"client_secret": {},
+		"authorization": {},
+	},
+}
+
+// RegisterSensitiveFields adds field names whose values will be redacted
+// whenever an error is rendered via Error(), String() or MarshalJSON.
+// Names are matched case insensitively.
+func RegisterSensitiveFields(names ...string) {
+	sensitiveFields.Lock()
+	defer sensitiveFields.Unlock()
+
+	for _, name := range names {
+		sensitiveFields.names[strings.ToLower(name)] = struct{}{}
+	}
+}
+
+// IsSensitiveField returns true if values of the named field are redacted.
+func IsSensitiveField(name string) bool {
+	sensitiveFields.RLock()
+	defer sensitiveFields.RUnlock()
+
+	_, ok := sensitiveFields.names[strings.ToLower(name)]
+	return ok
+}
+
+// Redacted holds a sensitive value that must never be rendered, regardless
+// of the field name it is stored under. The original value remains
+// available to code via Value.
+type Redacted struct {
+	Value interface{}
+}
+
+// Redact marks v as sensitive.
+func Redact(v interface{}) Redacted {
+	return Redacted{Value: v}
+}
+
+func (Redacted) String() string {
+	return RedactedText
+}
+
+// GoString ensures the value is redacted by %#v as well.
+func (Redacted) GoString() string {
+	return RedactedText
+}
+
+// MarshalJSON renders the redaction placeholder in place of the value.
+func (Redacted) MarshalJSON() ([]byte, error) {
+	return json.Marshal(RedactedText)
+}
+
+// Redact returns a copy of the fields, with the values of sensitive fields
+// replaced by a Redacted.
+func (f Fields) Redact() Fields {
+	if len(f) == 0 {
+		return f
+	}
+
+	redacted := make(Fields, len(f))
+	for k, v := range f {
+		if _, ok := v.(Redacted); !ok && IsSensitiveField(k) {
+			v = Redact(v)
+		}
+		redacted[k] = v
+	}
+	return redacted
+}
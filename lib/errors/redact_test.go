@@ -0,0 +1,66 @@
+package errors
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactSensitiveFields(t *testing.T) {
+	t.Parallel()
+
+	err := Wrap(fmt.Errorf("cause"), Fields{
+		"Password": "hunter2",
+		"key":      Redact("s3cr3t"),
+		"user":     "bob",
+	})
+
+	for _, s := range []string{err.Error(), err.String(), fmt.Sprintf("%+v", err)} {
+		require.NotContains(t, s, "hunter2")
+		require.NotContains(t, s, "s3cr3t")
+		require.Contains(t, s, "user=bob")
+		require.Contains(t, s, "Password="+RedactedText)
+	}
+
+	bits, jerr := json.Marshal(err)
+	require.NoError(t, jerr)
+	require.NotContains(t, string(bits), "hunter2")
+	require.NotContains(t, string(bits), "s3cr3t")
+
+	var m struct {
+		Fields map[string]interface{} `json:"fields"`
+	}
+	require.NoError(t, json.Unmarshal(bits, &m))
+	require.Equal(t, RedactedText, m.Fields["key"])
+	require.Equal(t, "bob", m.Fields["user"])
+
+	// original values remain available to code
+	require.Equal(t, "hunter2", err.Fields["Password"])
+	require.Equal(t, "s3cr3t", err.Fields["key"].(Redacted).Value)
+}
+
+// unregisterSensitiveFields undoes RegisterSensitiveFields, so that tests leave the registry as they found it
+func unregisterSensitiveFields(names ...string) {
+	sensitiveFields.Lock()
+	defer sensitiveFields.Unlock()
+
+	for _, name := range names {
+		delete(sensitiveFields.names, strings.ToLower(name))
+	}
+}
+
+func TestRegisterSensitiveFields(t *testing.T) {
+	t.Parallel()
+
+	require.False(t, IsSensitiveField("test_api_key"))
+	RegisterSensitiveFields("TEST_API_KEY")
+	t.Cleanup(func() { unregisterSensitiveFields("TEST_API_KEY") })
+	require.True(t, IsSensitiveField("test_api_key"))
+
+	err := NotFound(Fields{"test_api_key": "abc123"}, "missing")
+	require.NotContains(t, err.Error(), "abc123")
+	require.Equal(t, RedactedText, fmt.Sprintf("%#v", err.Fields.Redact()["test_api_key"]))
+}
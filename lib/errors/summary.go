@@ -0,0 +1,125 @@
+package errors
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// SummaryStackFrames limits the number of call stack frames kept by
+// Summarize. Zero means no limit.
+var SummaryStackFrames = 10
+
+// Summary holds the structured information carried by an error chain,
+// in a form suitable for structured loggers.
+type Summary struct {
+	// Message is the text of the error.
+	Message string
+	// Fields holds the redacted fields of every error in the chain. Fields
+	// of an outer wrap take precedence over those of the errors it wraps.
+	Fields Fields
+	// Cause is the text of the innermost error in the chain.
+	Cause string
+	// HTTPStatus is the http status of the first error in the chain
+	// supporting it, or zero if none does.
+	HTTPStatus int
+	// Stack is the innermost call stack found in the chain, without runtime
+	// and testing frames, limited to SummaryStackFrames.
+	Stack CallStack
+}
+
+// Summarize extracts the structured information carried by err. It
+// follows both standard Unwrap() error and Unwrap() []error methods, so
+// it works across Wrapped, HTTPError, Join, and fmt.Errorf %w chains.
+func Summarize(err error) *Summary {
+	if err == nil {
+		return nil
+	}
+
+	s := &Summary{
+		Message: err.Error(),
+		Cause:   err.Error(),
+	}
+	if status, ok := HTTPStatus(err); ok {
+		s.HTTPStatus = status
+	}
+
+	type stacker interface {
+		Stack() CallStack
+	}
+
+	var bases []*Base
+	seen := make(map[*Base]bool)
+	walk(err, func(e error, primary bool) {
+		if b, ok := e.(interface{ base() *Base }); ok && b.base() != nil && !seen[b.base()] {
+			seen[b.base()] = true
+			bases = append(bases, b.base())
+		}
+		if !primary {
+			return
+		}
+		s.Cause = e.Error()
+		if st, ok := e.(stacker); ok && len(st.Stack()) > 0 {
+			s.Stack = st.Stack()
+		}
+	})
+
+	// innermost first, so that outer wraps override
+	for i := len(bases) - 1; i >= 0; i-- {
+		if len(bases[i].Fields) > 0 {
+			s.Fields = combineFields(s.Fields, bases[i].Fields)
+		}
+	}
+	s.Fields = s.Fields.Redact()
+	s.Stack = trimStack(s.Stack, SummaryStackFrames)
+
+	return s
+}
+
+// Frames returns the summarized call stack as "func (file:line)" strings.
+func (s *Summary) Frames() []string {
+	frames := make([]string, len(s.Stack))
+	for i, c := range s.Stack {
+		frames[i] = fmt.Sprintf("%s (%s:%d)", path.Base(c.Function), c.File, c.Line)
+	}
+	return frames
+}
+
+// walk visits err and every error it wraps. primary is true for errors
+// reached only via single error Unwrap methods, i.e. on the cause path.
+func walk(err error, visit func(e error, primary bool)) {
+	var recurse func(err error, primary bool)
+	recurse = func(err error, primary bool) {
+		for err != nil {
+			visit(err, primary)
+			switch u := err.(type) {
+			case interface{ Unwrap() error }:
+				err = u.Unwrap()
+			case interface{ Unwrap() []error }:
+				for _, e := range u.Unwrap() {
+					recurse(e, false)
+				}
+				return
+			default:
+				return
+			}
+		}
+	}
+	recurse(err, true)
+}
+
+// trimStack drops runtime & testing frames, keeping at most max frames.
+func trimStack(stack CallStack, max int) CallStack {
+	var trimmed CallStack
+	for _, c := range stack {
+		fn := path.Base(c.Function)
+		if strings.HasPrefix(fn, "runtime.") || strings.HasPrefix(fn, "testing.") {
+			continue
+		}
+		if max > 0 && len(trimmed) == max {
+			break
+		}
+		trimmed = append(trimmed, c)
+	}
+	return trimmed
+}
@@ -0,0 +1,48 @@
+package errors
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummarize(t *testing.T) {
+	t.Parallel()
+
+	require.Nil(t, Summarize(nil))
+
+	terr := fmt.Errorf("outer: %w", Wrap(top2(), Fields{"topstr": "outer", "password": "hunter2"}))
+	s := Summarize(terr)
+
+	require.Equal(t, terr.Error(), s.Message)
+	require.Equal(t, "something not found nffield=1 other_nffield=2", s.Cause)
+	require.Equal(t, http.StatusNotFound, s.HTTPStatus)
+
+	// fields are merged across wraps, outer taking precedence
+	require.Equal(t, "outer", s.Fields["topstr"])
+	require.Equal(t, "middlestr", s.Fields["middlestr"])
+	require.Equal(t, 1, s.Fields["nffield"])
+	require.Equal(t, Redact("hunter2"), s.Fields["password"])
+
+	// stack is the cause's, trimmed of runtime & testing frames
+	require.Len(t, s.Stack, 4)
+	frames := s.Frames()
+	require.True(t, strings.HasPrefix(frames[0], "errors.bottom2 ("), frames[0])
+	require.True(t, strings.HasPrefix(frames[3], "errors.TestSummarize ("), frames[3])
+}
+
+func TestSummarizeJoin(t *testing.T) {
+	t.Parallel()
+
+	err := Join(io.EOF, BadRequest(Fields{"field": "name"}, "invalid"))
+	s := Summarize(err)
+
+	require.Equal(t, err.Error(), s.Cause)
+	require.Equal(t, http.StatusBadRequest, s.HTTPStatus)
+	require.Equal(t, "name", s.Fields["field"])
+	require.Equal(t, "errors.TestSummarizeJoin", s.Stack[0].Function[strings.LastIndex(s.Stack[0].Function, "/")+1:])
+}
@@ -1,14 +1,17 @@
 package errors
 
-import "fmt"
+import stderrors "errors"
 
 // Wrapf takes an originating "cause" error and annotates
 // it with text and the source file & line of the wrap point.
+// The format supports the %w verb; any such operands can be
+// matched by errors.Is & errors.As alongside the cause.
 func Wrapf(err error, fields Fields, format string, args ...interface{}) *Wrapped {
 	w := &Wrapped{
-		Base:  NewBase(1, fmt.Sprintf(format, args...)),
+		Base:  NewBase(1, ""),
 		cause: err,
 	}
+	w.Text, w.wrapped = formatWrapped(format, args...)
 	w.AddFields(fields)
 	return w
 }
@@ -61,9 +64,43 @@ func (w *Wrapped) With(fields Fields) *Wrapped {
 	return w
 }
 
-// Unwrap extracts any layered Wrapped errors inside of this one,
+// Unwrap returns the cause, allowing the standard library errors.Is,
+// errors.As & errors.Unwrap to see through a Wrapped.
+//
+// Unwrap used to return the layered wraps along with the original cause,
+// which is now done by Unwind: replace calls to w.Unwrap() expecting
+// (wraps, cause) with w.Unwind().
+func (w *Wrapped) Unwrap() error {
+	return w.cause
+}
+
+// Is reports whether any error wrapped by a %w verb in the text matches
+// target. The cause is checked by errors.Is via Unwrap.
+func (w *Wrapped) Is(target error) bool {
+	for _, err := range w.wrapped {
+		if stderrors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first error wrapped by a %w verb in the text that matches
+// target. The cause is checked by errors.As via Unwrap.
+func (w *Wrapped) As(target interface{}) bool {
+	for _, err := range w.wrapped {
+		if stderrors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Unwind extracts any layered Wrapped errors inside of this one,
 // returning the first non-Wrapped error found as the original cause.
-func (w *Wrapped) Unwrap() (wraps []*Base, cause error) {
+// It was named Unwrap before Wrapped supported the standard library
+// errors.Unwrap, see Wrapped.Unwrap.
+func (w *Wrapped) Unwind() (wraps []*Base, cause error) {
 	for w != nil {
 		cause = w.cause
 		wraps = append(wraps, w.Base)
@@ -87,7 +124,7 @@ func (w *Wrapped) Unwrap() (wraps []*Base, cause error) {
 func Cause(err error) (stack CallStack, wraps []*Base, cause error) {
 	cause = err
 	if w, ok := err.(*Wrapped); ok {
-		wraps, cause = w.Unwrap()
+		wraps, cause = w.Unwind()
 	}
 
 	type stacker interface {
@@ -0,0 +1,122 @@
+// Package authn authenticates server requests bearing a hub JWT, placing the token and the decoded
+// identity.DockerIdentity in the request context. See Authenticator.Middleware for net/http servers, and the
+// grpcauth package for gRPC servers.
+package authn
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/docker/licensing/lib/errors"
+	"github.com/docker/licensing/lib/go-auth/identity"
+	"github.com/docker/licensing/lib/go-auth/jwt"
+)
+
+var (
+	// ErrMissingToken returned (wrapped in a 401 error) when a request has no bearer token
+	ErrMissingToken = fmt.Errorf("missing bearer token")
+	// ErrInvalidToken returned (wrapped in a 401 error) when a request token fails to decode
+	ErrInvalidToken = fmt.Errorf("invalid token")
+	// ErrInsufficientScope returned (wrapped in a 403 error) when a request identity lacks a required scope
+	ErrInsufficientScope = fmt.Errorf("insufficient scope")
+)
+
+type keyType int
+
+// scopeMatchingContextKey holds the identity.ScopeMatching of the Authenticator which authenticated a context
+var scopeMatchingContextKey keyType
+
+// Options holds Authenticator options
+type Options struct {
+	// DecodeOptions are used to decode and validate tokens
+	DecodeOptions jwt.DecodeOptions
+
+	// RequiredScopes are scopes every authenticated identity must have. Scopes required by specific
+	// endpoints are checked with RequireScopes.
+	RequiredScopes []string
+
+	// ScopeMatching selects how the scopes of identities cover required scopes, here and in RequireScopes.
+	// Defaults to identity.MatchExact.
+	ScopeMatching identity.ScopeMatching
+}
+
+// Authenticator authenticates requests bearing a hub JWT
+type Authenticator struct {
+	options Options
+}
+
+// New returns an Authenticator with the given options
+func New(options Options) *Authenticator {
+	return &Authenticator{
+		options: options,
+	}
+}
+
+// Authenticate decodes the token and checks the identity has the required scopes, returning a context carrying
+// both the token and the identity. Errors are *errors.HTTPError with a 401 or 403 status, wrapping
+// ErrMissingToken, ErrInvalidToken or ErrInsufficientScope.
+func (a *Authenticator) Authenticate(ctx context.Context, token string) (context.Context, error) {
+	if token == "" {
+		return nil, errors.NewHTTPErrorf(http.StatusUnauthorized, "%w", ErrMissingToken)
+	}
+
+	id, err := jwt.Decode(token, a.options.DecodeOptions)
+	if err != nil {
+		return nil, errors.NewHTTPErrorf(http.StatusUnauthorized, "%w: %w", ErrInvalidToken, err)
+	}
+
+	if err := CheckScopesMatching(id, a.options.ScopeMatching, a.options.RequiredScopes...); err != nil {
+		return nil, err
+	}
+
+	ctx = jwt.NewContext(ctx, token)
+	ctx = identity.NewContext(ctx, id)
+	ctx = context.WithValue(ctx, scopeMatchingContextKey, a.options.ScopeMatching)
+	return ctx, nil
+}
+
+// RequireScopes returns a 403 error if the identity in ctx lacks any of the given scopes, or a 401 error if
+// ctx carries no identity. Scopes are matched as configured by the Authenticator which authenticated ctx.
+func RequireScopes(ctx context.Context, scopes ...string) error {
+	id, ok := identity.FromContext(ctx)
+	if !ok {
+		return errors.NewHTTPErrorf(http.StatusUnauthorized, "%w", ErrMissingToken)
+	}
+	m, _ := ctx.Value(scopeMatchingContextKey).(identity.ScopeMatching)
+	return CheckScopesMatching(id, m, scopes...)
+}
+
+// CheckScopes returns a 403 error if id lacks any of the exact given scopes, see CheckScopesMatching.
+func CheckScopes(id *identity.DockerIdentity, scopes ...string) error {
+	return CheckScopesMatching(id, identity.MatchExact, scopes...)
+}
+
+// CheckScopesMatching returns a 403 error if id lacks any of the given scopes, as matched by m. The scopes of a
+// delegated identity are those granted to its token, the error fields list its actors.
+func CheckScopesMatching(id *identity.DockerIdentity, m identity.ScopeMatching, scopes ...string) error {
+	missing := id.MissingScopesMatching(m, scopes...)
+	if len(missing) == 0 {
+		return nil
+	}
+
+	fields := errors.Fields{
+		"docker_id":      id.DockerID,
+		"missing_scopes": strings.Join(missing, " "),
+	}
+	if id.IsDelegated() {
+		// the services acting for the user, current first
+		fields["actors"] = strings.Join(id.DelegationChain(), " ")
+	}
+	return errors.NewHTTPErrorf(http.StatusForbidden, "%w", ErrInsufficientScope).With(fields)
+}
+
+// BearerToken returns the token of a bearer Authorization header value, or an empty string if there is none
+func BearerToken(authorization string) string {
+	const prefix = "bearer "
+	if len(authorization) <= len(prefix) || !strings.EqualFold(authorization[:len(prefix)], prefix) {
+		return ""
+	}
+	return strings.TrimSpace(authorization[len(prefix):])
+}
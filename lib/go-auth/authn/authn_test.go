@@ -0,0 +1,184 @@
+package authn_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/docker/licensing/lib/errors"
+	"github.com/docker/licensing/lib/go-auth/authn"
+	"github.com/docker/licensing/lib/go-auth/identity"
+	"github.com/docker/licensing/lib/go-auth/jwt"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newSigner returns decode options trusting a new key, and a function signing tokens with it
+func newSigner(t *testing.T) (jwt.DecodeOptions, func(scopes ...string) string) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	require.NoError(t, err)
+	signingKey := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+	jwk, err := jwt.NewJSONWebKey("test", public)
+	require.NoError(t, err)
+	keySet, err := jwt.NewStaticKeySet(&jwt.JSONWebKeySet{Keys: []jwt.JSONWebKey{jwk}})
+	require.NoError(t, err)
+
+	sign := func(scopes ...string) string {
+		token, err := jwt.Encode(identity.DockerIdentity{
+			DockerID: "00557eca-6a92-4b97-8af2-f966572ac11e",
+			Username: "testuser",
+			Scopes:   scopes,
+		}, jwt.EncodeOptions{
+			SigningKey: signingKey,
+			Algorithm:  jwt.EdDSA,
+			KeyID:      "test",
+			Expiration: time.Now().Add(time.Hour).Unix(),
+		})
+		require.NoError(t, err)
+		return token
+	}
+
+	return jwt.DecodeOptions{KeySet: keySet}, sign
+}
+
+func TestAuthenticate(t *testing.T) {
+	t.Parallel()
+
+	decodeOptions, sign := newSigner(t)
+	a := authn.New(authn.Options{
+		DecodeOptions:  decodeOptions,
+		RequiredScopes: []string{"billing"},
+	})
+
+	token := sign("billing", "store")
+	ctx, err := a.Authenticate(context.Background(), token)
+	require.NoError(t, err)
+
+	ctxToken, ok := jwt.FromContext(ctx)
+	require.True(t, ok)
+	require.Equal(t, token, ctxToken)
+	id, ok := identity.FromContext(ctx)
+	require.True(t, ok)
+	require.Equal(t, "testuser", id.Username)
+
+	require.NoError(t, authn.RequireScopes(ctx, "store"))
+	// scopes are matched exactly by default
+	err = authn.RequireScopes(ctx, "store:read")
+	require.True(t, errors.Is(err, authn.ErrInsufficientScope))
+	err = authn.RequireScopes(ctx, "admin")
+	require.True(t, errors.Is(err, authn.ErrInsufficientScope))
+	status, _ := errors.HTTPStatus(err)
+	require.Equal(t, http.StatusForbidden, status)
+
+	_, err = a.Authenticate(context.Background(), "")
+	require.True(t, errors.Is(err, authn.ErrMissingToken))
+	status, _ = errors.HTTPStatus(err)
+	require.Equal(t, http.StatusUnauthorized, status)
+
+	_, err = a.Authenticate(context.Background(), "foo")
+	require.True(t, errors.Is(err, authn.ErrInvalidToken))
+	var ve *jwt.ValidationError
+	require.True(t, errors.As(err, &ve))
+	status, _ = errors.HTTPStatus(err)
+	require.Equal(t, http.StatusUnauthorized, status)
+
+	_, err = a.Authenticate(context.Background(), sign("store"))
+	require.True(t, errors.Is(err, authn.ErrInsufficientScope))
+	require.Regexp(t, "missing_scopes=billing", err)
+}
+
+func TestAuthenticateHierarchicalScopes(t *testing.T) {
+	t.Parallel()
+
+	decodeOptions, sign := newSigner(t)
+	a := authn.New(authn.Options{
+		DecodeOptions:  decodeOptions,
+		RequiredScopes: []string{"billing:read"},
+		ScopeMatching:  identity.MatchHierarchical,
+	})
+
+	ctx, err := a.Authenticate(context.Background(), sign("billing", "repo:*"))
+	require.NoError(t, err)
+	require.NoError(t, authn.RequireScopes(ctx, "repo:write", "billing:write"))
+	err = authn.RequireScopes(ctx, "admin")
+	require.True(t, errors.Is(err, authn.ErrInsufficientScope))
+
+	_, err = a.Authenticate(context.Background(), sign("repo:*"))
+	require.True(t, errors.Is(err, authn.ErrInsufficientScope))
+}
+
+func TestCheckScopesDelegated(t *testing.T) {
+	t.Parallel()
+
+	id := &identity.DockerIdentity{
+		DockerID: "00557eca-6a92-4b97-8af2-f966572ac11e",
+		Scopes:   []string{"billing:read"},
+		Actor:    &identity.Actor{Subject: "billing-gateway", Actor: &identity.Actor{Subject: "store-frontend"}},
+	}
+
+	require.NoError(t, authn.CheckScopes(id, "billing:read"))
+
+	err := authn.CheckScopes(id, "billing:write")
+	require.True(t, errors.Is(err, authn.ErrInsufficientScope))
+	require.Regexp(t, "actors=billing-gateway store-frontend", err)
+}
+
+func TestBearerToken(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "abc", authn.BearerToken("Bearer abc"))
+	require.Equal(t, "abc", authn.BearerToken("bearer  abc "))
+	require.Equal(t, "", authn.BearerToken("Basic abc"))
+	require.Equal(t, "", authn.BearerToken("Bearer "))
+	require.Equal(t, "", authn.BearerToken(""))
+}
+
+func TestMiddleware(t *testing.T) {
+	t.Parallel()
+
+	decodeOptions, sign := newSigner(t)
+	a := authn.New(authn.Options{DecodeOptions: decodeOptions})
+
+	handler := a.Middleware(authn.RequireScopesMiddleware("billing")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, ok := identity.FromContext(r.Context())
+		require.True(t, ok)
+		w.Write([]byte(id.Username))
+	})))
+
+	serve := func(authorization string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/", nil)
+		if authorization != "" {
+			r.Header.Set("Authorization", authorization)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	w := serve("Bearer " + sign("billing"))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "testuser", w.Body.String())
+
+	w = serve("")
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+	require.Equal(t, "Bearer", w.Header().Get("WWW-Authenticate"))
+
+	w = serve("Bearer foo")
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+	require.Equal(t, `Bearer error="invalid_token"`, w.Header().Get("WWW-Authenticate"))
+	require.NotContains(t, w.Body.String(), "malformed")
+
+	w = serve("Bearer " + sign("store"))
+	require.Equal(t, http.StatusForbidden, w.Code)
+	require.Equal(t, `Bearer error="insufficient_scope"`, w.Header().Get("WWW-Authenticate"))
+}
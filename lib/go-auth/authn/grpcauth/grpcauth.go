@@ -0,0 +1,101 @@
+// Package grpcauth provides gRPC server interceptors authenticating requests with an authn.Authenticator
+package grpcauth
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/docker/licensing/lib/errors"
+	"github.com/docker/licensing/lib/go-auth/authn"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// authorizationKey is the metadata key of the bearer token, as sent by grpc credentials.PerRPCCredentials
+const authorizationKey = "authorization"
+
+// UnaryServerInterceptor returns an interceptor authenticating unary calls with their bearer authorization
+// metadata. The handler is called with a context carrying the token and identity.
+func UnaryServerInterceptor(a *authn.Authenticator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := authenticate(ctx, a)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns an interceptor authenticating streams with their bearer authorization
+// metadata. The handler is called with a stream whose context carries the token and identity.
+func StreamServerInterceptor(a *authn.Authenticator) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticate(ss.Context(), a)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// RequireScopes returns a status error if the identity in ctx lacks any of the given scopes. It is meant to
+// be called by handlers with endpoint specific scopes.
+func RequireScopes(ctx context.Context, scopes ...string) error {
+	if err := authn.RequireScopes(ctx, scopes...); err != nil {
+		return Status(err)
+	}
+	return nil
+}
+
+// Status converts an authn error to a gRPC status error: 401 errors to Unauthenticated, 403 errors to
+// PermissionDenied. Only the status text is sent, so that token validation details are not leaked.
+func Status(err error) error {
+	httpStatus, _ := errors.HTTPStatus(err)
+
+	code := codes.Internal
+	switch httpStatus {
+	case http.StatusUnauthorized:
+		code = codes.Unauthenticated
+	case http.StatusForbidden:
+		code = codes.PermissionDenied
+	}
+
+	msg := http.StatusText(httpStatus)
+	switch {
+	case errors.Is(err, authn.ErrMissingToken):
+		msg = authn.ErrMissingToken.Error()
+	case errors.Is(err, authn.ErrInvalidToken):
+		msg = authn.ErrInvalidToken.Error()
+	case errors.Is(err, authn.ErrInsufficientScope):
+		msg = authn.ErrInsufficientScope.Error()
+	}
+
+	return status.Error(code, msg)
+}
+
+func authenticate(ctx context.Context, a *authn.Authenticator) (context.Context, error) {
+	var token string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(authorizationKey); len(values) > 0 {
+			token = authn.BearerToken(values[0])
+		}
+	}
+
+	ctx, err := a.Authenticate(ctx, token)
+	if err != nil {
+		return nil, Status(err)
+	}
+	return ctx, nil
+}
+
+// serverStream overrides the context of a grpc.ServerStream
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,125 @@
+package grpcauth_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/docker/licensing/lib/go-auth/authn"
+	"github.com/docker/licensing/lib/go-auth/authn/grpcauth"
+	"github.com/docker/licensing/lib/go-auth/identity"
+	"github.com/docker/licensing/lib/go-auth/jwt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newAuthenticator(t *testing.T) (*authn.Authenticator, func(scopes ...string) string) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	require.NoError(t, err)
+	signingKey := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+	jwk, err := jwt.NewJSONWebKey("test", public)
+	require.NoError(t, err)
+	keySet, err := jwt.NewStaticKeySet(&jwt.JSONWebKeySet{Keys: []jwt.JSONWebKey{jwk}})
+	require.NoError(t, err)
+
+	sign := func(scopes ...string) string {
+		token, err := jwt.Encode(identity.DockerIdentity{
+			DockerID: "00557eca-6a92-4b97-8af2-f966572ac11e",
+			Username: "testuser",
+			Scopes:   scopes,
+		}, jwt.EncodeOptions{
+			SigningKey: signingKey,
+			Algorithm:  jwt.EdDSA,
+			KeyID:      "test",
+			Expiration: time.Now().Add(time.Hour).Unix(),
+		})
+		require.NoError(t, err)
+		return token
+	}
+
+	return authn.New(authn.Options{
+		DecodeOptions:  jwt.DecodeOptions{KeySet: keySet},
+		RequiredScopes: []string{"billing"},
+	}), sign
+}
+
+func withToken(token string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	t.Parallel()
+
+	a, sign := newAuthenticator(t)
+	interceptor := grpcauth.UnaryServerInterceptor(a)
+	info := &grpc.UnaryServerInfo{FullMethod: "/billing.Billing/GetSubscription"}
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		if err := grpcauth.RequireScopes(ctx, "admin"); err != nil {
+			return nil, err
+		}
+		id, _ := identity.FromContext(ctx)
+		return id.Username, nil
+	}
+
+	_, err := interceptor(withToken(sign("billing")), nil, info, handler)
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	require.Equal(t, "insufficient scope", status.Convert(err).Message())
+
+	res, err := interceptor(withToken(sign("billing", "admin")), nil, info, handler)
+	require.NoError(t, err)
+	require.Equal(t, "testuser", res)
+
+	_, err = interceptor(context.Background(), nil, info, handler)
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+	require.Equal(t, "missing bearer token", status.Convert(err).Message())
+
+	_, err = interceptor(withToken("foo"), nil, info, handler)
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+	require.Equal(t, "invalid token", status.Convert(err).Message())
+
+	_, err = interceptor(withToken(sign("store")), nil, info, handler)
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+type testServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *testServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	t.Parallel()
+
+	a, sign := newAuthenticator(t)
+	interceptor := grpcauth.StreamServerInterceptor(a)
+	info := &grpc.StreamServerInfo{FullMethod: "/billing.Billing/WatchSubscriptions", IsServerStream: true}
+
+	token := sign("billing")
+	var streamToken string
+	handler := func(srv interface{}, ss grpc.ServerStream) error {
+		streamToken, _ = jwt.FromContext(ss.Context())
+		return nil
+	}
+
+	require.NoError(t, interceptor(nil, &testServerStream{ctx: withToken(token)}, info, handler))
+	require.Equal(t, token, streamToken)
+
+	err := interceptor(nil, &testServerStream{ctx: context.Background()}, info, handler)
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+}
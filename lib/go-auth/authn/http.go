@@ -0,0 +1,52 @@
+package authn
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/docker/licensing/lib/errors"
+)
+
+// Middleware authenticates requests with their bearer Authorization header, calling next with a request context
+// carrying the token and identity. Rejected requests are answered with WriteError.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, err := a.Authenticate(r.Context(), BearerToken(r.Header.Get("Authorization")))
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequireScopesMiddleware returns middleware rejecting requests whose identity lacks any of the given scopes. It
+// must be wrapped by Authenticator.Middleware.
+func RequireScopesMiddleware(scopes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := RequireScopes(r.Context(), scopes...); err != nil {
+				WriteError(w, err)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// WriteError answers a rejected request with the status of err and a WWW-Authenticate challenge, as per
+// RFC 6750. The response body holds only the status text, so that token validation details are not leaked.
+func WriteError(w http.ResponseWriter, err error) {
+	status, _ := errors.HTTPStatus(err)
+
+	switch {
+	case errors.Is(err, ErrMissingToken):
+		w.Header().Set("WWW-Authenticate", "Bearer")
+	case errors.Is(err, ErrInvalidToken):
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf("Bearer error=%q", "invalid_token"))
+	case errors.Is(err, ErrInsufficientScope):
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf("Bearer error=%q", "insufficient_scope"))
+	}
+
+	http.Error(w, http.StatusText(status), status)
+}
@@ -0,0 +1,46 @@
+package identity
+
+import (
+	"log/slog"
+	"strings"
+)
+
+// Actor identifies a party acting on behalf of an identity, such as a service calling an endpoint for a user.
+// See the act claim of https://tools.ietf.org/html/rfc8693#section-4.1
+type Actor struct {
+	// Subject identifies the acting party
+	Subject string
+
+	// Actor is the party that delegated to this actor, if the delegation went through several parties
+	Actor *Actor
+}
+
+// IsDelegated returns true if another party acts on behalf of the identity.
+func (di DockerIdentity) IsDelegated() bool {
+	return di.Actor != nil
+}
+
+// DelegationChain returns the subjects of the parties acting on behalf of the identity, from the current actor
+// to the earliest one. It is empty if the identity is not delegated.
+func (di DockerIdentity) DelegationChain() []string {
+	var chain []string
+	for a := di.Actor; a != nil; a = a.Actor {
+		chain = append(chain, a.Subject)
+	}
+	return chain
+}
+
+// LogValue implements slog.LogValuer, describing the identity and its delegation chain in audit logs.
+func (di DockerIdentity) LogValue() slog.Value {
+	attrs := []slog.Attr{
+		slog.String("docker_id", di.DockerID),
+		slog.String("username", di.Username),
+	}
+	if len(di.Scopes) > 0 {
+		attrs = append(attrs, slog.String("scopes", strings.Join(di.Scopes, " ")))
+	}
+	if di.IsDelegated() {
+		attrs = append(attrs, slog.Any("actors", di.DelegationChain()))
+	}
+	return slog.GroupValue(attrs...)
+}
@@ -0,0 +1,64 @@
+package identity
+
+import (
+	"encoding/json"
+	"math"
+)
+
+// Claim returns the named custom claim, see DockerIdentity.Claims.
+func (di DockerIdentity) Claim(name string) (interface{}, bool) {
+	v, ok := di.Claims[name]
+	return v, ok
+}
+
+// StringClaim returns the named custom claim if it is a string.
+func (di DockerIdentity) StringClaim(name string) (string, bool) {
+	s, ok := di.Claims[name].(string)
+	return s, ok
+}
+
+// StringsClaim returns the named custom claim if it is a list of strings. A single string is returned as a list.
+func (di DockerIdentity) StringsClaim(name string) ([]string, bool) {
+	switch v := di.Claims[name].(type) {
+	case string:
+		return []string{v}, true
+	case []string:
+		return v, true
+	case []interface{}:
+		strs := make([]string, 0, len(v))
+		for _, e := range v {
+			s, ok := e.(string)
+			if !ok {
+				return nil, false
+			}
+			strs = append(strs, s)
+		}
+		return strs, true
+	}
+	return nil, false
+}
+
+// IntClaim returns the named custom claim if it is an integer.
+func (di DockerIdentity) IntClaim(name string) (int64, bool) {
+	switch v := di.Claims[name].(type) {
+	case int:
+		return int64(v), true
+	case int64:
+		return v, true
+	case float64:
+		// decoded json numbers are float64
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return int64(v), true
+		}
+	case json.Number:
+		i, err := v.Int64()
+		return i, err == nil
+	}
+	return 0, false
+}
+
+// BoolClaim returns the named custom claim if it is a boolean.
+func (di DockerIdentity) BoolClaim(name string) (bool, bool) {
+	b, ok := di.Claims[name].(bool)
+	return b, ok
+}
@@ -12,23 +12,80 @@ type DockerIdentity struct {
 	FullName string
 	Email    string
 	Scopes   []string
+
+	// Claims holds the custom claims of the identity token, such as org ids or the entitlement tier, see the
+	// typed accessors StringClaim, StringsClaim, IntClaim and BoolClaim.
+	Claims map[string]interface{}
+
+	// Actor is the party acting on behalf of the identity, if the identity was delegated
+	Actor *Actor
 }
 
 func (di DockerIdentity) String() string {
+	if di.IsDelegated() {
+		return fmt.Sprintf("{docker_id=%v, username=%v, email=%v, scopes=%v, actors=%v}",
+			di.DockerID, di.Username, di.Email, di.Scopes, di.DelegationChain())
+	}
 	return fmt.Sprintf("{docker_id=%v, username=%v, email=%v, scopes=%v}",
 		di.DockerID, di.Username, di.Email, di.Scopes)
 }
 
 // HasScope returns true if the exact input scope is present in the scopes list.
 func (di DockerIdentity) HasScope(scope string) bool {
+	return di.HasScopeMatching(MatchExact, scope)
+}
+
+// HasScopeMatching returns true if the input scope is covered by a scope of the scopes list, as matched by m.
+func (di DockerIdentity) HasScopeMatching(m ScopeMatching, scope string) bool {
 	for i := range di.Scopes {
-		if di.Scopes[i] == scope {
+		if m.Covers(di.Scopes[i], scope) {
 			return true
 		}
 	}
 	return false
 }
 
+// HasAllScopes returns true if every exact input scope is present in the scopes list.
+func (di DockerIdentity) HasAllScopes(scopes ...string) bool {
+	return di.HasAllScopesMatching(MatchExact, scopes...)
+}
+
+// HasAllScopesMatching returns true if every input scope is covered by the scopes list, as matched by m.
+func (di DockerIdentity) HasAllScopesMatching(m ScopeMatching, scopes ...string) bool {
+	return len(di.MissingScopesMatching(m, scopes...)) == 0
+}
+
+// HasAnyScope returns true if any exact input scope is present in the scopes list.
+func (di DockerIdentity) HasAnyScope(scopes ...string) bool {
+	return di.HasAnyScopeMatching(MatchExact, scopes...)
+}
+
+// HasAnyScopeMatching returns true if any input scope is covered by the scopes list, as matched by m.
+func (di DockerIdentity) HasAnyScopeMatching(m ScopeMatching, scopes ...string) bool {
+	for _, scope := range scopes {
+		if di.HasScopeMatching(m, scope) {
+			return true
+		}
+	}
+	return false
+}
+
+// MissingScopes returns the input scopes not present in the scopes list.
+func (di DockerIdentity) MissingScopes(scopes ...string) []string {
+	return di.MissingScopesMatching(MatchExact, scopes...)
+}
+
+// MissingScopesMatching returns the input scopes not covered by the scopes list, as matched by m.
+func (di DockerIdentity) MissingScopesMatching(m ScopeMatching, scopes ...string) []string {
+	var missing []string
+	for _, scope := range scopes {
+		if !di.HasScopeMatching(m, scope) {
+			missing = append(missing, scope)
+		}
+	}
+	return missing
+}
+
 type keyType int
 
 var identityContextKey keyType
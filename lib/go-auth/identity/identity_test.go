@@ -1,7 +1,9 @@
 package identity_test
 
 import (
+	"bytes"
 	"context"
+	"log/slog"
 	"testing"
 
 	"github.com/docker/licensing/lib/go-auth/identity"
@@ -44,3 +46,176 @@ func TestDockerIdentity_String(t *testing.T) {
 
 	require.NotEmpty(t, d.String())
 }
+
+func TestParseScope(t *testing.T) {
+	t.Parallel()
+
+	s, err := identity.ParseScope("repo:read")
+	require.NoError(t, err)
+	require.Equal(t, identity.Scope{Resource: "repo", Action: "read"}, s)
+	require.Equal(t, "repo:read", s.String())
+
+	s, err = identity.ParseScope("org:docker:admin")
+	require.NoError(t, err)
+	require.Equal(t, identity.Scope{Resource: "org:docker", Action: "admin"}, s)
+
+	s, err = identity.ParseScope("admin")
+	require.NoError(t, err)
+	require.Equal(t, identity.Scope{Resource: "admin"}, s)
+	require.Equal(t, "admin", s.String())
+
+	for _, invalid := range []string{"", ":", "repo:", ":read", "repo::read"} {
+		_, err = identity.ParseScope(invalid)
+		require.Error(t, err, invalid)
+	}
+}
+
+func TestHasScope(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		granted      string
+		required     string
+		exact        bool
+		hierarchical bool
+	}{
+		{"repo:read", "repo:read", true, true},
+		{"repo:read", "repo:write", false, false},
+		{"repo", "repo:read", false, true},
+		{"repo", "repo:read:tags", false, true},
+		{"repo:read", "repo", false, false},
+		{"repo:*", "repo:read", false, true},
+		{"repo:*", "repo:read:tags", false, true},
+		{"repo:*", "repo", false, false},
+		{"repo:*", "org:read", false, false},
+		{"*:read", "repo:read", false, true},
+		{"*:read", "repo:write", false, false},
+		{"*", "admin", false, true},
+		{"*", "repo:read", false, true},
+		{"admin", "administrator", false, false},
+		{"repo:read", "repo:*", false, false},
+		{"repo:*", "repo:*", true, true},
+		// invalid scopes only match exactly
+		{"repo:", "repo:", true, true},
+		{"repo:", "repo:read", false, false},
+	}
+
+	for _, c := range cases {
+		d := identity.DockerIdentity{Scopes: []string{c.granted}}
+		require.Equal(t, c.exact, d.HasScope(c.required), "%s has %s", c.granted, c.required)
+		require.Equal(t, c.exact, d.HasScopeMatching(identity.MatchExact, c.required), "%s has %s", c.granted, c.required)
+		require.Equal(t, c.hierarchical, d.HasScopeMatching(identity.MatchHierarchical, c.required), "%s covers %s", c.granted, c.required)
+	}
+}
+
+func TestLiteralScopesKeepTheirMeaning(t *testing.T) {
+	t.Parallel()
+
+	// scopes of tokens issued before hierarchical matching only grant themselves by default
+	d := defaultIdentity()
+	d.Scopes = []string{"repo", "*", "billing:*"}
+
+	require.True(t, d.HasAllScopes("repo", "*", "billing:*"))
+	require.False(t, d.HasAnyScope("repo:read", "admin", "billing:read"))
+	require.Equal(t, []string{"repo:write", "billing:read"}, d.MissingScopes("repo:write", "billing:read", "*"))
+}
+
+func TestHasAllAndAnyScopes(t *testing.T) {
+	t.Parallel()
+
+	d := defaultIdentity()
+	d.Scopes = []string{"repo:read", "billing:read"}
+
+	require.True(t, d.HasAllScopes("repo:read", "billing:read"))
+	require.False(t, d.HasAllScopes("repo:read", "billing:write"))
+	require.True(t, d.HasAllScopes())
+
+	require.True(t, d.HasAnyScope("admin", "billing:read"))
+	require.False(t, d.HasAnyScope("admin", "billing:write"))
+	require.False(t, d.HasAnyScope())
+
+	require.Equal(t, []string{"billing:write", "admin"}, d.MissingScopes("repo:read", "billing:write", "admin"))
+
+	d.Scopes = []string{"repo:*", "billing:read"}
+	require.Equal(t, []string{"repo:read", "billing:write"}, d.MissingScopes("repo:read", "billing:write"))
+	require.Equal(t, []string{"billing:write"}, d.MissingScopesMatching(identity.MatchHierarchical, "repo:read", "billing:write"))
+
+	// wildcards are literal unless hierarchical matching is asked for
+	require.False(t, d.HasAllScopes("repo:read", "billing:read"))
+	require.True(t, d.HasAllScopesMatching(identity.MatchExact, "repo:*", "billing:read"))
+	require.False(t, d.HasAllScopesMatching(identity.MatchExact, "repo:read", "billing:read"))
+	require.True(t, d.HasAllScopesMatching(identity.MatchHierarchical, "repo:read", "repo:write", "billing:read"))
+	require.False(t, d.HasAllScopesMatching(identity.MatchHierarchical, "repo:read", "billing:write"))
+
+	require.False(t, d.HasAnyScope("repo:read", "billing:write"))
+	require.False(t, d.HasAnyScopeMatching(identity.MatchExact, "repo:read", "billing:write"))
+	require.True(t, d.HasAnyScopeMatching(identity.MatchHierarchical, "repo:read", "billing:write"))
+	require.False(t, d.HasAnyScopeMatching(identity.MatchHierarchical, "admin", "billing:write"))
+}
+
+func TestClaimAccessors(t *testing.T) {
+	t.Parallel()
+
+	d := defaultIdentity()
+	d.Claims = map[string]interface{}{
+		"tier":    "enterprise",
+		"org_ids": []interface{}{"org-1", "org-2"},
+		"seats":   float64(25),
+		"ratio":   0.5,
+		"trial":   true,
+		"mixed":   []interface{}{"org-1", 2},
+	}
+
+	tier, ok := d.StringClaim("tier")
+	require.True(t, ok)
+	require.Equal(t, "enterprise", tier)
+	_, ok = d.StringClaim("seats")
+	require.False(t, ok)
+
+	orgs, ok := d.StringsClaim("org_ids")
+	require.True(t, ok)
+	require.Equal(t, []string{"org-1", "org-2"}, orgs)
+	orgs, ok = d.StringsClaim("tier")
+	require.True(t, ok)
+	require.Equal(t, []string{"enterprise"}, orgs)
+	_, ok = d.StringsClaim("mixed")
+	require.False(t, ok)
+
+	seats, ok := d.IntClaim("seats")
+	require.True(t, ok)
+	require.Equal(t, int64(25), seats)
+	_, ok = d.IntClaim("ratio")
+	require.False(t, ok)
+
+	trial, ok := d.BoolClaim("trial")
+	require.True(t, ok)
+	require.True(t, trial)
+
+	_, ok = d.Claim("missing")
+	require.False(t, ok)
+	_, ok = identity.DockerIdentity{}.StringClaim("tier")
+	require.False(t, ok)
+}
+
+func TestDelegation(t *testing.T) {
+	t.Parallel()
+
+	d := defaultIdentity()
+	require.False(t, d.IsDelegated())
+	require.Empty(t, d.DelegationChain())
+	require.NotContains(t, d.String(), "actors")
+
+	d.Actor = &identity.Actor{
+		Subject: "billing-gateway",
+		Actor:   &identity.Actor{Subject: "store-frontend"},
+	}
+	require.True(t, d.IsDelegated())
+	require.Equal(t, []string{"billing-gateway", "store-frontend"}, d.DelegationChain())
+	require.Contains(t, d.String(), "actors=[billing-gateway store-frontend]")
+
+	var buf bytes.Buffer
+	slog.New(slog.NewJSONHandler(&buf, nil)).Info("request", "identity", d)
+	require.Contains(t, buf.String(), `"docker_id":"10557eca-6a92-4b97-8af2-f966572ac11e"`)
+	require.Contains(t, buf.String(), `"actors":["billing-gateway","store-frontend"]`)
+	require.NotContains(t, buf.String(), d.Email)
+}
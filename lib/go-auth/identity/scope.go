@@ -0,0 +1,112 @@
+package identity
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// scopeSeparator separates the segments of a scope, e.g. repo:read
+	scopeSeparator = ":"
+
+	// scopeWildcard matches any segment. As the last segment of a granted scope, it matches any number of
+	// trailing segments.
+	scopeWildcard = "*"
+)
+
+// Scope is a parsed scope. Scopes are hierarchical lists of segments separated by colons, the last of which is
+// the action performed on the resource named by the others, e.g. repo:read. A scope with a single segment, e.g.
+// admin, names a resource with no specific action.
+//
+// With MatchHierarchical, a granted scope covers a required scope if it is equal to it or one of its ancestors,
+// with * segments matching any segment: repo covers repo:read, repo:* covers repo:read and repo:write, *:read
+// covers repo:read and org:read, and * covers every scope.
+type Scope struct {
+	Resource string
+	Action   string
+}
+
+// ParseScope parses a scope, returning an error if it is empty or has empty segments
+func ParseScope(s string) (Scope, error) {
+	segments := strings.Split(s, scopeSeparator)
+	for _, segment := range segments {
+		if segment == "" {
+			return Scope{}, fmt.Errorf("invalid scope %q: empty segment", s)
+		}
+	}
+
+	if len(segments) == 1 {
+		return Scope{Resource: s}, nil
+	}
+
+	last := len(segments) - 1
+	return Scope{
+		Resource: strings.Join(segments[:last], scopeSeparator),
+		Action:   segments[last],
+	}, nil
+}
+
+func (s Scope) String() string {
+	if s.Action == "" {
+		return s.Resource
+	}
+	return s.Resource + scopeSeparator + s.Action
+}
+
+// Covers returns true if the scope, when granted, covers the required scope
+func (s Scope) Covers(required Scope) bool {
+	granted := s.segments()
+	segments := required.segments()
+
+	for i, g := range granted {
+		if i == len(segments) {
+			// the granted scope is more specific than the required scope
+			return false
+		}
+		if g == scopeWildcard && i == len(granted)-1 {
+			return true
+		}
+		if g != scopeWildcard && g != segments[i] {
+			return false
+		}
+	}
+
+	// equal, or an ancestor of the required scope
+	return true
+}
+
+func (s Scope) segments() []string {
+	return strings.Split(s.String(), scopeSeparator)
+}
+
+// ScopeMatching selects how granted scopes match required scopes
+type ScopeMatching int
+
+const (
+	// MatchExact only matches a required scope with an equal granted scope. * and hierarchy have no meaning.
+	MatchExact ScopeMatching = iota
+	// MatchHierarchical also matches a required scope with granted ancestors and wildcards, see Scope. It must
+	// only be used with issuers granting scopes with this meaning.
+	MatchHierarchical
+)
+
+// Covers returns true if the granted scope string covers the required one. With MatchHierarchical, scopes which
+// cannot be parsed only match exactly.
+func (m ScopeMatching) Covers(granted, required string) bool {
+	if granted == required {
+		return true
+	}
+	if m != MatchHierarchical {
+		return false
+	}
+
+	g, err := ParseScope(granted)
+	if err != nil {
+		return false
+	}
+	r, err := ParseScope(required)
+	if err != nil {
+		return false
+	}
+	return g.Covers(r)
+}
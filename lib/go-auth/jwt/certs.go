@@ -0,0 +1,223 @@
+package jwt
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrCertificateRevoked is returned by CertificateRevocationCheckers for revoked certificates
+var ErrCertificateRevoked = fmt.Errorf("certificate revoked")
+
+// CertificateOptions holds x5c certificate chain validation options. The certificates following the first one
+// in the x5c header are used as intermediates to build a chain to DecodeOptions.CertificateChain.
+type CertificateOptions struct {
+	// ExtKeyUsages are the extended key usages the chain must allow. As with x509.VerifyOptions, defaults to
+	// x509.ExtKeyUsageServerAuth: use x509.ExtKeyUsageAny to accept any.
+	ExtKeyUsages []x509.ExtKeyUsage
+
+	// KeyUsage holds the key usages the signing certificate must allow, if it restricts them. Defaults to
+	// x509.KeyUsageDigitalSignature.
+	KeyUsage x509.KeyUsage
+
+	// RevocationChecker, if set, is called for each certificate of the chain but the root
+	RevocationChecker CertificateRevocationChecker
+
+	// PinnedKeys, if set, are the SPKI hashes (see SPKIHash) of which a certificate of the chain must have one
+	PinnedKeys []string
+}
+
+// CertificateRevocationChecker checks whether certificates are revoked, e.g. against CRLs or OCSP responders
+type CertificateRevocationChecker interface {
+	// CheckCertificate returns an error wrapping ErrCertificateRevoked if cert, issued by issuer, is revoked,
+	// or any other error if its revocation status is unknown.
+	CheckCertificate(cert, issuer *x509.Certificate) error
+}
+
+// CertificateRevocationCheckerFunc adapts a function to a CertificateRevocationChecker
+type CertificateRevocationCheckerFunc func(cert, issuer *x509.Certificate) error
+
+// CheckCertificate implements CertificateRevocationChecker
+func (f CertificateRevocationCheckerFunc) CheckCertificate(cert, issuer *x509.Certificate) error {
+	return f(cert, issuer)
+}
+
+// CRLChecker is a CertificateRevocationChecker checking certificates against certificate revocation lists.
+// Certificates whose issuer has no CRL are not revoked.
+type CRLChecker struct {
+	crls []*x509.RevocationList
+}
+
+// NewCRLChecker returns a CRLChecker checking certificates against the given CRLs
+func NewCRLChecker(crls ...*x509.RevocationList) *CRLChecker {
+	return &CRLChecker{crls: crls}
+}
+
+// CheckCertificate implements CertificateRevocationChecker. CRLs must be signed by the certificate issuer, and
+// up to date.
+func (c *CRLChecker) CheckCertificate(cert, issuer *x509.Certificate) error {
+	for _, crl := range c.crls {
+		if !bytes.Equal(crl.RawIssuer, issuer.RawSubject) {
+			continue
+		}
+
+		if err := crl.CheckSignatureFrom(issuer); err != nil {
+			return fmt.Errorf("invalid CRL: %s", err)
+		}
+		if !crl.NextUpdate.IsZero() && time.Now().After(crl.NextUpdate) {
+			return fmt.Errorf("outdated CRL: next update was due %s", crl.NextUpdate.Format(time.RFC3339))
+		}
+
+		for _, entry := range crl.RevokedCertificateEntries {
+			if entry.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+				return fmt.Errorf("%w: serial %s revoked on %s", ErrCertificateRevoked, cert.SerialNumber,
+					entry.RevocationTime.Format(time.RFC3339))
+			}
+		}
+	}
+	return nil
+}
+
+// SPKIHash returns the base64 encoded SHA-256 hash of the certificate subject public key info, as used for
+// public key pinning
+func SPKIHash(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// CertificateErrorReason identifies why a certificate failed validation
+type CertificateErrorReason string
+
+// Certificate validation failure reasons
+const (
+	CertificateMalformed        CertificateErrorReason = "malformed"
+	CertificateUntrusted        CertificateErrorReason = "untrusted"
+	CertificateKeyUsage         CertificateErrorReason = "key_usage"
+	CertificateRevoked          CertificateErrorReason = "revoked"
+	CertificateRevocationFailed CertificateErrorReason = "revocation_check_failed"
+	CertificateNotPinned        CertificateErrorReason = "not_pinned"
+)
+
+// CertificateError is returned when the x5c certificate chain fails validation. It is the cause of the
+// *ValidationError returned by Decode, see errors.As.
+type CertificateError struct {
+	// Index of the failing certificate in the x5c header, or -1 if it is not from the header, e.g. a root
+	Index int
+	// Cert is the failing certificate, nil if it is malformed
+	Cert *x509.Certificate
+
+	Reason CertificateErrorReason
+	Err    error
+}
+
+func (e *CertificateError) Error() string {
+	name := "certificate"
+	if e.Index >= 0 {
+		name = fmt.Sprintf("certificate x5c[%d]", e.Index)
+	}
+	if e.Cert != nil {
+		name = fmt.Sprintf("%s (%s)", name, e.Cert.Subject)
+	}
+
+	if e.Reason == CertificateMalformed {
+		return fmt.Sprintf("failed to parse %s: [%v]", name, e.Err)
+	}
+	return fmt.Sprintf("failed to verify %s: [%v]", name, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *CertificateError) Unwrap() error {
+	return e.Err
+}
+
+// validateChain validates the x5c certificate chain against roots. If valid, the signing certificate is returned.
+func validateChain(chain []*x509.Certificate, roots *x509.CertPool, options CertificateOptions) (*x509.Certificate, error) {
+	leaf := chain[0]
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	verified, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     options.ExtKeyUsages,
+	})
+	if err != nil {
+		return nil, certificateError(chain, failingCert(err, leaf), CertificateUntrusted, err)
+	}
+
+	keyUsage := options.KeyUsage
+	if keyUsage == 0 {
+		keyUsage = x509.KeyUsageDigitalSignature
+	}
+	if leaf.KeyUsage != 0 && leaf.KeyUsage&keyUsage != keyUsage {
+		return nil, certificateError(chain, leaf, CertificateKeyUsage, fmt.Errorf("key usage does not allow signatures"))
+	}
+
+	if len(options.PinnedKeys) > 0 && !pinned(verified, options.PinnedKeys) {
+		return nil, certificateError(chain, leaf, CertificateNotPinned, fmt.Errorf("no certificate of the chain matches a pinned key"))
+	}
+
+	if options.RevocationChecker != nil {
+		// intermediates with several possible issuers yield several chains, the first one is checked
+		path := verified[0]
+		for i := 0; i < len(path)-1; i++ {
+			if err := options.RevocationChecker.CheckCertificate(path[i], path[i+1]); err != nil {
+				reason := CertificateRevocationFailed
+				if errors.Is(err, ErrCertificateRevoked) {
+					reason = CertificateRevoked
+				}
+				return nil, certificateError(chain, path[i], reason, err)
+			}
+		}
+	}
+
+	return leaf, nil
+}
+
+// failingCert returns the certificate named by a x509 verification error, or leaf if it names none
+func failingCert(err error, leaf *x509.Certificate) *x509.Certificate {
+	var unknownAuthority x509.UnknownAuthorityError
+	if errors.As(err, &unknownAuthority) && unknownAuthority.Cert != nil {
+		return unknownAuthority.Cert
+	}
+	var invalid x509.CertificateInvalidError
+	if errors.As(err, &invalid) && invalid.Cert != nil {
+		return invalid.Cert
+	}
+	return leaf
+}
+
+func certificateError(chain []*x509.Certificate, cert *x509.Certificate, reason CertificateErrorReason, err error) *CertificateError {
+	index := -1
+	for i := range chain {
+		if chain[i].Equal(cert) {
+			index = i
+			break
+		}
+	}
+	return &CertificateError{
+		Index:  index,
+		Cert:   cert,
+		Reason: reason,
+		Err:    err,
+	}
+}
+
+// pinned returns true if a certificate of the chains has a pinned key
+func pinned(chains [][]*x509.Certificate, pins []string) bool {
+	for _, chain := range chains {
+		for _, cert := range chain {
+			if contains(pins, SPKIHash(cert)) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,238 @@
+package jwt_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/docker/licensing/lib/go-auth/jwt"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCert(t *testing.T, template *x509.Certificate, parent *testCA) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+
+	parentCert, parentKey := template, key
+	if parent != nil {
+		parentCert, parentKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parentCert, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testCA{cert: cert, key: key}
+}
+
+func newTestCA(t *testing.T, name string, serial int64, parent *testCA) *testCA {
+	return newTestCert(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: name},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}, parent)
+}
+
+type testPKI struct {
+	root, intermediate, leaf *testCA
+}
+
+func newTestPKI(t *testing.T, leafKeyUsage x509.KeyUsage, leafExtKeyUsage ...x509.ExtKeyUsage) *testPKI {
+	root := newTestCA(t, "Test Root", 1, nil)
+	intermediate := newTestCA(t, "Test Intermediate", 2, root)
+	leaf := newTestCert(t, &x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "Test Signing"},
+		KeyUsage:     leafKeyUsage,
+		ExtKeyUsage:  leafExtKeyUsage,
+	}, intermediate)
+
+	return &testPKI{root: root, intermediate: intermediate, leaf: leaf}
+}
+
+func (p *testPKI) roots() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(p.root.cert)
+	return pool
+}
+
+// token returns a token signed by the leaf key, with the given certificates in its x5c header
+func (p *testPKI) token(t *testing.T, certs ...*x509.Certificate) string {
+	der, err := x509.MarshalECPrivateKey(p.leaf.key)
+	require.NoError(t, err)
+
+	var chain []byte
+	for _, cert := range certs {
+		chain = append(chain, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})...)
+	}
+
+	tokenStr, err := jwt.Encode(defaultIdentity(), jwt.EncodeOptions{
+		SigningKey:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}),
+		Certificate: chain,
+		Algorithm:   jwt.ES256,
+		Expiration:  time.Now().Add(time.Hour).Unix(),
+	})
+	require.NoError(t, err)
+	return tokenStr
+}
+
+func requireCertificateError(t *testing.T, err error, reason jwt.CertificateErrorReason, index int) *jwt.CertificateError {
+	require.Error(t, err)
+	var certErr *jwt.CertificateError
+	require.True(t, errors.As(err, &certErr), "expected a *jwt.CertificateError, got %v", err)
+	require.Equal(t, reason, certErr.Reason)
+	require.Equal(t, index, certErr.Index)
+	return certErr
+}
+
+func TestIntermediateCertificates(t *testing.T) {
+	t.Parallel()
+
+	pki := newTestPKI(t, x509.KeyUsageDigitalSignature)
+	options := jwt.DecodeOptions{CertificateChain: pki.roots()}
+
+	_, err := jwt.Decode(pki.token(t, pki.leaf.cert, pki.intermediate.cert), options)
+	require.NoError(t, err)
+
+	// the chain cannot be built without the intermediate
+	_, err = jwt.Decode(pki.token(t, pki.leaf.cert), options)
+	certErr := requireCertificateError(t, err, jwt.CertificateUntrusted, 0)
+	require.Equal(t, pki.leaf.cert, certErr.Cert)
+	require.Regexp(t, `failed to verify certificate x5c\[0\] \(CN=Test Signing\)`, err)
+	require.Regexp(t, "certificate signed by unknown authority", err)
+}
+
+func TestMalformedCertificate(t *testing.T) {
+	t.Parallel()
+
+	pki := newTestPKI(t, x509.KeyUsageDigitalSignature)
+
+	bogus := &x509.Certificate{Raw: []byte("bogus")}
+	_, err := jwt.Decode(pki.token(t, pki.leaf.cert, bogus), jwt.DecodeOptions{CertificateChain: pki.roots()})
+	requireCertificateError(t, err, jwt.CertificateMalformed, 1)
+	require.Regexp(t, `failed to parse certificate x5c\[1\]`, err)
+}
+
+func TestCertificateKeyUsage(t *testing.T) {
+	t.Parallel()
+
+	pki := newTestPKI(t, x509.KeyUsageKeyEncipherment)
+	_, err := jwt.Decode(pki.token(t, pki.leaf.cert, pki.intermediate.cert), jwt.DecodeOptions{
+		CertificateChain: pki.roots(),
+	})
+	requireCertificateError(t, err, jwt.CertificateKeyUsage, 0)
+}
+
+func TestCertificateExtKeyUsage(t *testing.T) {
+	t.Parallel()
+
+	pki := newTestPKI(t, x509.KeyUsageDigitalSignature, x509.ExtKeyUsageCodeSigning)
+	tokenStr := pki.token(t, pki.leaf.cert, pki.intermediate.cert)
+
+	// server auth is required by default
+	_, err := jwt.Decode(tokenStr, jwt.DecodeOptions{CertificateChain: pki.roots()})
+	requireCertificateError(t, err, jwt.CertificateUntrusted, 0)
+
+	_, err = jwt.Decode(tokenStr, jwt.DecodeOptions{
+		CertificateChain: pki.roots(),
+		Certificates: jwt.CertificateOptions{
+			ExtKeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		},
+	})
+	require.NoError(t, err)
+}
+
+func TestCertificatePinning(t *testing.T) {
+	t.Parallel()
+
+	pki := newTestPKI(t, x509.KeyUsageDigitalSignature)
+	other := newTestCA(t, "Other", 4, nil)
+	tokenStr := pki.token(t, pki.leaf.cert, pki.intermediate.cert)
+
+	for _, pinned := range []*testCA{pki.root, pki.intermediate, pki.leaf} {
+		_, err := jwt.Decode(tokenStr, jwt.DecodeOptions{
+			CertificateChain: pki.roots(),
+			Certificates: jwt.CertificateOptions{
+				PinnedKeys: []string{jwt.SPKIHash(other.cert), jwt.SPKIHash(pinned.cert)},
+			},
+		})
+		require.NoError(t, err)
+	}
+
+	_, err := jwt.Decode(tokenStr, jwt.DecodeOptions{
+		CertificateChain: pki.roots(),
+		Certificates: jwt.CertificateOptions{
+			PinnedKeys: []string{jwt.SPKIHash(other.cert)},
+		},
+	})
+	requireCertificateError(t, err, jwt.CertificateNotPinned, 0)
+}
+
+func TestCertificateRevocation(t *testing.T) {
+	t.Parallel()
+
+	pki := newTestPKI(t, x509.KeyUsageDigitalSignature)
+	tokenStr := pki.token(t, pki.leaf.cert, pki.intermediate.cert)
+
+	newCRL := func(issuer *testCA, serials ...*big.Int) *x509.RevocationList {
+		var entries []x509.RevocationListEntry
+		for _, serial := range serials {
+			entries = append(entries, x509.RevocationListEntry{SerialNumber: serial, RevocationTime: time.Now()})
+		}
+		der, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+			Number:                    big.NewInt(1),
+			ThisUpdate:                time.Now().Add(-time.Minute),
+			NextUpdate:                time.Now().Add(time.Hour),
+			RevokedCertificateEntries: entries,
+		}, issuer.cert, issuer.key)
+		require.NoError(t, err)
+		crl, err := x509.ParseRevocationList(der)
+		require.NoError(t, err)
+		return crl
+	}
+
+	decode := func(checker jwt.CertificateRevocationChecker) error {
+		_, err := jwt.Decode(tokenStr, jwt.DecodeOptions{
+			CertificateChain: pki.roots(),
+			Certificates: jwt.CertificateOptions{
+				RevocationChecker: checker,
+			},
+		})
+		return err
+	}
+
+	require.NoError(t, decode(jwt.NewCRLChecker(newCRL(pki.intermediate, big.NewInt(42)), newCRL(pki.root))))
+
+	err := decode(jwt.NewCRLChecker(newCRL(pki.intermediate, pki.leaf.cert.SerialNumber)))
+	requireCertificateError(t, err, jwt.CertificateRevoked, 0)
+	require.True(t, errors.Is(err, jwt.ErrCertificateRevoked))
+
+	err = decode(jwt.NewCRLChecker(newCRL(pki.root, pki.intermediate.cert.SerialNumber)))
+	certErr := requireCertificateError(t, err, jwt.CertificateRevoked, 1)
+	require.Equal(t, pki.intermediate.cert, certErr.Cert)
+
+	err = decode(jwt.CertificateRevocationCheckerFunc(func(cert, issuer *x509.Certificate) error {
+		return errors.New("OCSP responder unavailable")
+	}))
+	requireCertificateError(t, err, jwt.CertificateRevocationFailed, 0)
+	require.Regexp(t, "OCSP responder unavailable", err)
+}
@@ -0,0 +1,236 @@
+package jwt
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/docker/licensing/lib/go-auth/identity"
+)
+
+// CheckName names a token validation check
+type CheckName string
+
+// Token validation checks, in the order Inspect runs them
+const (
+	CheckFormat         CheckName = "format"
+	CheckAlgorithm      CheckName = "algorithm"
+	CheckSignature      CheckName = "signature"
+	CheckExpiry         CheckName = "expiry"
+	CheckNotBefore      CheckName = "not_before"
+	CheckIssuedAt       CheckName = "issued_at"
+	CheckIssuer         CheckName = "issuer"
+	CheckAudience       CheckName = "audience"
+	CheckRequiredClaims CheckName = "required_claims"
+	CheckRevocation     CheckName = "revocation"
+	CheckIdentity       CheckName = "identity"
+	CheckScopes         CheckName = "scopes"
+)
+
+// Check is the result of a token validation check
+type Check struct {
+	Name CheckName `json:"name"`
+
+	// Passed is true if the token passed the check. Skipped checks have not passed.
+	Passed bool `json:"passed"`
+	// Skipped is true if the check does not apply, e.g. the issuer check when no issuer is expected
+	Skipped bool `json:"skipped,omitempty"`
+
+	// Reason explains why the check failed or was skipped
+	Reason string `json:"reason,omitempty"`
+}
+
+// CertificateInfo details a certificate of the x5c header
+type CertificateInfo struct {
+	Subject      string    `json:"subject,omitempty"`
+	Issuer       string    `json:"issuer,omitempty"`
+	SerialNumber *big.Int  `json:"serial_number,omitempty"`
+	NotBefore    time.Time `json:"not_before,omitempty"`
+	NotAfter     time.Time `json:"not_after,omitempty"`
+	IsCA         bool      `json:"is_ca,omitempty"`
+	SPKIHash     string    `json:"spki_hash,omitempty"`
+
+	// Error is set if the certificate cannot be parsed
+	Error string `json:"error,omitempty"`
+}
+
+// Inspection details a token and the results of its validation, see Inspect
+type Inspection struct {
+	Header       map[string]interface{}   `json:"header,omitempty"`
+	Claims       map[string]interface{}   `json:"claims,omitempty"`
+	Certificates []CertificateInfo        `json:"certificates,omitempty"`
+	Identity     *identity.DockerIdentity `json:"identity,omitempty"`
+
+	Checks []Check `json:"checks"`
+	// Valid is true if Decode would accept the token, and the identity has the inspected scopes
+	Valid bool `json:"valid"`
+}
+
+// Check returns the result of the named check
+func (i *Inspection) Check(name CheckName) (Check, bool) {
+	for _, c := range i.Checks {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return Check{}, false
+}
+
+// Failed returns the checks the token failed
+func (i *Inspection) Failed() []Check {
+	var failed []Check
+	for _, c := range i.Checks {
+		if !c.Passed && !c.Skipped {
+			failed = append(failed, c)
+		}
+	}
+	return failed
+}
+
+// Inspect decodes the token without failing, for debugging purposes: it returns the token header, claims and
+// certificates, and the result of each check Decode makes with the given options. If scopes are given, the
+// identity is also checked to have them. Claims are returned even if the token signature is invalid: do NOT
+// use them for anything but debugging.
+func Inspect(tokenStr string, options DecodeOptions, scopes ...string) *Inspection {
+	in := &Inspection{}
+	add := func(name CheckName, err error) {
+		c := Check{Name: name, Passed: err == nil}
+		if err != nil {
+			c.Reason = err.Error()
+		}
+		in.Checks = append(in.Checks, c)
+	}
+	skip := func(name CheckName, reason string) {
+		in.Checks = append(in.Checks, Check{Name: name, Skipped: true, Reason: reason})
+	}
+
+	claims, err := in.decode(tokenStr)
+	add(CheckFormat, err)
+	if err != nil {
+		return in
+	}
+
+	algorithms := options.Algorithms
+	if len(algorithms) == 0 {
+		algorithms = DefaultAlgorithms
+	}
+	alg, _ := in.Header["alg"].(string)
+	if contains(algorithms, alg) {
+		add(CheckAlgorithm, nil)
+	} else {
+		add(CheckAlgorithm, fmt.Errorf("signing method %q is not allowed", alg))
+	}
+
+	_, err = (&jwt.Parser{ValidMethods: algorithms, SkipClaimsValidation: true}).Parse(tokenStr, keyFunc(options))
+	if ve, ok := err.(*jwt.ValidationError); ok {
+		err = newValidationError(ve, "")
+	}
+	add(CheckSignature, err)
+
+	failures := map[CheckName][]string{}
+	for _, f := range checkClaims(claims, options) {
+		failures[f.check] = append(failures[f.check], f.message)
+	}
+	claimCheck := func(name CheckName, applies bool, skipReason string) {
+		switch {
+		case len(failures[name]) > 0:
+			add(name, fmt.Errorf("%s", strings.Join(failures[name], ", ")))
+		case !applies:
+			skip(name, skipReason)
+		default:
+			add(name, nil)
+		}
+	}
+	claimCheck(CheckExpiry, claims[exp] != nil, "no exp claim")
+	claimCheck(CheckNotBefore, claims[nbf] != nil, "no nbf claim")
+	claimCheck(CheckIssuedAt, claims[iat] != nil, "no iat claim")
+	claimCheck(CheckIssuer, len(options.Issuers) > 0, "no expected issuers")
+	claimCheck(CheckAudience, len(options.Audiences) > 0, "no expected audiences")
+	claimCheck(CheckRequiredClaims, len(options.RequiredClaims) > 0, "no required claims")
+
+	if options.RevocationChecker != nil {
+		add(CheckRevocation, checkRevocation(claims, options.RevocationChecker))
+	} else {
+		skip(CheckRevocation, "no revocation checker")
+	}
+
+	in.Identity, err = identityFromClaims(claims)
+	add(CheckIdentity, err)
+
+	switch {
+	case len(scopes) == 0:
+		skip(CheckScopes, "no scopes to check")
+	case in.Identity == nil:
+		add(CheckScopes, fmt.Errorf("no identity"))
+	default:
+		if missing := in.Identity.MissingScopes(scopes...); len(missing) > 0 {
+			add(CheckScopes, fmt.Errorf("missing scopes %q", missing))
+		} else {
+			add(CheckScopes, nil)
+		}
+	}
+
+	in.Valid = len(in.Failed()) == 0
+	return in
+}
+
+// decode decodes the header, claims and certificates of the token
+func (i *Inspection) decode(tokenStr string) (jwt.MapClaims, error) {
+	parts := strings.Split(tokenStr, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed token: expected 3 segments, got %d", len(parts))
+	}
+
+	token, err := new(jwt.Parser).Parse(tokenStr, nil)
+	if token == nil {
+		return nil, err
+	}
+
+	i.Header = token.Header
+	for _, v := range headerCertificates(token.Header) {
+		i.Certificates = append(i.Certificates, inspectCertificate(v))
+	}
+
+	claims, err := unverifiedClaims(tokenStr)
+	if err != nil {
+		return nil, err
+	}
+	i.Claims = claims
+	return claims, nil
+}
+
+func headerCertificates(header map[string]interface{}) []interface{} {
+	certs, _ := header[x5c].([]interface{})
+	return certs
+}
+
+func inspectCertificate(v interface{}) CertificateInfo {
+	s, ok := v.(string)
+	if !ok {
+		return CertificateInfo{Error: "x5c token header was not a string"}
+	}
+
+	der, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return CertificateInfo{Error: err.Error()}
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return CertificateInfo{Error: fmt.Sprintf("failed to parse certificate: [%v]", err)}
+	}
+
+	return CertificateInfo{
+		Subject:      cert.Subject.String(),
+		Issuer:       cert.Issuer.String(),
+		SerialNumber: cert.SerialNumber,
+		NotBefore:    cert.NotBefore,
+		NotAfter:     cert.NotAfter,
+		IsCA:         cert.IsCA,
+		SPKIHash:     SPKIHash(cert),
+	}
+}
@@ -0,0 +1,108 @@
+package jwt_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/docker/licensing/lib/go-auth/jwt"
+
+	"github.com/stretchr/testify/require"
+)
+
+func requireCheck(t *testing.T, in *jwt.Inspection, name jwt.CheckName, passed, skipped bool) jwt.Check {
+	c, ok := in.Check(name)
+	require.True(t, ok, "missing check %s", name)
+	require.Equal(t, passed, c.Passed, "check %s: %s", name, c.Reason)
+	require.Equal(t, skipped, c.Skipped, "check %s: %s", name, c.Reason)
+	return c
+}
+
+func TestInspectValidToken(t *testing.T) {
+	t.Parallel()
+
+	tokenStr := encodeTrusted(t, jwt.EncodeOptions{Issuer: "hub"})
+	in := jwt.Inspect(tokenStr, jwt.DecodeOptions{
+		CertificateChain: defaultRootCertChain(t),
+		Issuers:          []string{"hub"},
+	}, "scopea")
+
+	require.True(t, in.Valid)
+	require.Empty(t, in.Failed())
+	require.Equal(t, "RS256", in.Header["alg"])
+	require.Equal(t, "hub", in.Claims["iss"])
+	require.Len(t, in.Certificates, 1)
+	require.Empty(t, in.Certificates[0].Error)
+	require.NotEmpty(t, in.Certificates[0].SPKIHash)
+	require.Equal(t, defaultIdentity().DockerID, in.Identity.DockerID)
+
+	for _, name := range []jwt.CheckName{jwt.CheckFormat, jwt.CheckAlgorithm, jwt.CheckSignature, jwt.CheckExpiry,
+		jwt.CheckIssuedAt, jwt.CheckIssuer, jwt.CheckIdentity, jwt.CheckScopes} {
+		requireCheck(t, in, name, true, false)
+	}
+	for _, name := range []jwt.CheckName{jwt.CheckNotBefore, jwt.CheckAudience, jwt.CheckRequiredClaims, jwt.CheckRevocation} {
+		requireCheck(t, in, name, false, true)
+	}
+}
+
+func TestInspectInvalidToken(t *testing.T) {
+	t.Parallel()
+
+	tokenStr := encodeTrusted(t, jwt.EncodeOptions{
+		Issuer:     "other",
+		Expiration: time.Now().Add(-time.Hour).Unix(),
+	})
+	// untrusted: no certificate chain
+	in := jwt.Inspect(tokenStr, jwt.DecodeOptions{Issuers: []string{"hub"}}, "scopec")
+
+	require.False(t, in.Valid)
+	// claims are reported even though the token cannot be verified
+	require.Equal(t, "other", in.Claims["iss"])
+	require.NotNil(t, in.Identity)
+
+	c := requireCheck(t, in, jwt.CheckSignature, false, false)
+	require.NotEmpty(t, c.Reason)
+	c = requireCheck(t, in, jwt.CheckExpiry, false, false)
+	require.Regexp(t, "expired", c.Reason)
+	requireCheck(t, in, jwt.CheckIssuer, false, false)
+	c = requireCheck(t, in, jwt.CheckScopes, false, false)
+	require.Regexp(t, "scopec", c.Reason)
+	requireCheck(t, in, jwt.CheckAlgorithm, true, false)
+
+	failed := map[jwt.CheckName]bool{}
+	for _, c := range in.Failed() {
+		failed[c.Name] = true
+	}
+	require.Equal(t, map[jwt.CheckName]bool{
+		jwt.CheckSignature: true,
+		jwt.CheckExpiry:    true,
+		jwt.CheckIssuer:    true,
+		jwt.CheckScopes:    true,
+	}, failed)
+}
+
+func TestInspectMalformedToken(t *testing.T) {
+	t.Parallel()
+
+	for _, tokenStr := range []string{"", "a.b", "not.a.token"} {
+		in := jwt.Inspect(tokenStr, jwt.DecodeOptions{})
+		require.False(t, in.Valid)
+		requireCheck(t, in, jwt.CheckFormat, false, false)
+		require.Len(t, in.Checks, 1)
+	}
+}
+
+func TestInspectRevokedToken(t *testing.T) {
+	t.Parallel()
+
+	tokenStr := encodeTrusted(t, jwt.EncodeOptions{Jti: "token-1"})
+	revoked := jwt.NewMemoryRevocationList()
+	revoked.Revoke("token-1", time.Time{})
+
+	in := jwt.Inspect(tokenStr, jwt.DecodeOptions{
+		CertificateChain:  defaultRootCertChain(t),
+		RevocationChecker: revoked,
+	})
+	require.False(t, in.Valid)
+	requireCheck(t, in, jwt.CheckRevocation, false, false)
+	requireCheck(t, in, jwt.CheckSignature, true, false)
+}
@@ -0,0 +1,390 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// key id header field
+	// https://tools.ietf.org/html/rfc7515#section-4.1.4
+	kid = "kid"
+
+	// jwks responses are read up to this many bytes
+	jwksMaxLength = 1 << 20
+)
+
+// ErrKeyNotFound is returned when a key set holds no key with the requested id
+var ErrKeyNotFound = fmt.Errorf("key not found")
+
+// KeySet holds the public keys with which tokens are verified, identified by their key id.
+type KeySet interface {
+	// Key returns the public key with the given id, or an error wrapping ErrKeyNotFound if there is none.
+	Key(keyID string) (crypto.PublicKey, error)
+}
+
+// JSONWebKey is a public JSON Web Key, see https://tools.ietf.org/html/rfc7517.
+// RSA, EC (P-256, P-384 & P-521) and OKP (Ed25519) keys are supported.
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid,omitempty"`
+	Use       string `json:"use,omitempty"`
+	Algorithm string `json:"alg,omitempty"`
+
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+
+	// EC & OKP
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+	Y     string `json:"y,omitempty"`
+
+	// base64 encoded DER x509 certificate chain, used if the key parameters are absent
+	X5c []string `json:"x5c,omitempty"`
+}
+
+// JSONWebKeySet is a set of JSON Web Keys, as published by JWKS endpoints.
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// NewJSONWebKey returns the JSON Web Key of the given RSA, ECDSA or Ed25519 public key.
+func NewJSONWebKey(keyID string, key crypto.PublicKey) (JSONWebKey, error) {
+	enc := base64.RawURLEncoding
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return JSONWebKey{
+			KeyType: "RSA",
+			KeyID:   keyID,
+			N:       enc.EncodeToString(k.N.Bytes()),
+			E:       enc.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+		}, nil
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		return JSONWebKey{
+			KeyType: "EC",
+			KeyID:   keyID,
+			Curve:   k.Curve.Params().Name,
+			X:       enc.EncodeToString(k.X.FillBytes(make([]byte, size))),
+			Y:       enc.EncodeToString(k.Y.FillBytes(make([]byte, size))),
+		}, nil
+	case ed25519.PublicKey:
+		return JSONWebKey{
+			KeyType: "OKP",
+			KeyID:   keyID,
+			Curve:   "Ed25519",
+			X:       enc.EncodeToString(k),
+		}, nil
+	}
+	return JSONWebKey{}, fmt.Errorf("unsupported key type %T", key)
+}
+
+// PublicKey returns the public key described by the JSON Web Key.
+func (k JSONWebKey) PublicKey() (crypto.PublicKey, error) {
+	dec := base64.RawURLEncoding
+	decode := func(name, value string) ([]byte, error) {
+		b, err := dec.DecodeString(strings.TrimRight(value, "="))
+		if err != nil || len(b) == 0 {
+			return nil, fmt.Errorf("key %s: invalid %s parameter", k.KeyID, name)
+		}
+		return b, nil
+	}
+
+	if k.N == "" && k.X == "" && len(k.X5c) > 0 {
+		der, err := base64.StdEncoding.DecodeString(k.X5c[0])
+		if err != nil {
+			return nil, fmt.Errorf("key %s: invalid x5c parameter", k.KeyID)
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: failed to parse certificate: [%v]", k.KeyID, err)
+		}
+		return cert.PublicKey, nil
+	}
+
+	switch k.KeyType {
+	case "RSA":
+		n, err := decode("n", k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode("e", k.E)
+		if err != nil {
+			return nil, err
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("key %s: invalid e parameter", k.KeyID)
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Curve {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("key %s: unsupported curve %q", k.KeyID, k.Curve)
+		}
+		x, err := decode("x", k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode("y", k.Y)
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, fmt.Errorf("key %s: point not on curve %s", k.KeyID, k.Curve)
+		}
+		return key, nil
+
+	case "OKP":
+		if k.Curve != "Ed25519" {
+			return nil, fmt.Errorf("key %s: unsupported curve %q", k.KeyID, k.Curve)
+		}
+		x, err := decode("x", k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("key %s: invalid x parameter", k.KeyID)
+		}
+		return ed25519.PublicKey(x), nil
+	}
+
+	return nil, fmt.Errorf("key %s: unsupported key type %q", k.KeyID, k.KeyType)
+}
+
+// keys parses the signing keys of the set, indexed by key id. Keys that are not used for signatures are skipped.
+func (s *JSONWebKeySet) keys() (map[string]crypto.PublicKey, error) {
+	keys := make(map[string]crypto.PublicKey, len(s.Keys))
+	var firstErr error
+	for _, k := range s.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		// skip keys of unsupported types, so that publishing a new kind of key doesn't break verification
+		key, err := k.PublicKey()
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		keys[k.KeyID] = key
+	}
+	if len(keys) == 0 && firstErr != nil {
+		return nil, firstErr
+	}
+	return keys, nil
+}
+
+// StaticKeySet is a KeySet of fixed keys.
+type StaticKeySet struct {
+	keys map[string]crypto.PublicKey
+}
+
+// NewStaticKeySet returns a KeySet holding the keys of the given JSON Web Key Set.
+func NewStaticKeySet(jwks *JSONWebKeySet) (*StaticKeySet, error) {
+	keys, err := jwks.keys()
+	if err != nil {
+		return nil, err
+	}
+	return &StaticKeySet{keys: keys}, nil
+}
+
+// ParseKeySet returns a StaticKeySet holding the keys of the given JSON encoded JSON Web Key Set.
+func ParseKeySet(data []byte) (*StaticKeySet, error) {
+	var jwks JSONWebKeySet
+	if err := json.Unmarshal(data, &jwks); err != nil {
+		return nil, fmt.Errorf("invalid key set: %s", err)
+	}
+	return NewStaticKeySet(&jwks)
+}
+
+// Key implements KeySet.
+func (s *StaticKeySet) Key(keyID string) (crypto.PublicKey, error) {
+	key, ok := s.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrKeyNotFound, keyID)
+	}
+	return key, nil
+}
+
+// RemoteKeySetOptions holds RemoteKeySet options
+type RemoteKeySetOptions struct {
+	// HTTPClient fetches the key set, defaults to a client with a 30 second timeout
+	HTTPClient *http.Client
+
+	// CacheTTL is how long the key set is cached, unless the response sets a Cache-Control max-age.
+	// Defaults to an hour.
+	CacheTTL time.Duration
+
+	// MinRefreshInterval limits how often the key set is fetched again when a token refers to an unknown key,
+	// as happens after a key rotation, or while the jwks url is failing. Defaults to a minute.
+	MinRefreshInterval time.Duration
+}
+
+// RemoteKeySet is a KeySet fetched from a JWKS url. The key set is cached, and fetched again once it expires,
+// or when a token refers to a key it does not hold.
+type RemoteKeySet struct {
+	url     string
+	options RemoteKeySetOptions
+
+	mu      sync.Mutex
+	keys    map[string]crypto.PublicKey
+	fetched time.Time // when the key set was last fetched, successfully or not
+	updated time.Time // when the current keys were fetched
+	expires time.Time
+}
+
+// NewRemoteKeySet returns a KeySet fetching its keys from the given JWKS url.
+func NewRemoteKeySet(url string, options RemoteKeySetOptions) *RemoteKeySet {
+	if options.HTTPClient == nil {
+		options.HTTPClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	if options.CacheTTL == 0 {
+		options.CacheTTL = time.Hour
+	}
+	if options.MinRefreshInterval == 0 {
+		options.MinRefreshInterval = time.Minute
+	}
+
+	return &RemoteKeySet{
+		url:     url,
+		options: options,
+	}
+}
+
+// Key implements KeySet.
+func (s *RemoteKeySet) Key(keyID string) (crypto.PublicKey, error) {
+	s.mu.Lock()
+	now := time.Now()
+	key, ok := s.keys[keyID]
+	if ok && now.Before(s.expires) {
+		s.mu.Unlock()
+		return key, nil
+	}
+
+	// refresh an expired key set, or look for a rotated key, unless the key set was just fetched
+	refresh := s.fetched.IsZero() || now.Sub(s.fetched) >= s.options.MinRefreshInterval
+	if refresh {
+		s.fetched = now
+	}
+	s.mu.Unlock()
+
+	if refresh {
+		if err := s.refresh(now); err != nil {
+			// keep using the keys of a stale key set while the jwks endpoint is failing
+			if ok {
+				return key, nil
+			}
+			return nil, err
+		}
+
+		s.mu.Lock()
+		key, ok = s.keys[keyID]
+		s.mu.Unlock()
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrKeyNotFound, keyID)
+	}
+	return key, nil
+}
+
+// Refresh fetches the key set again.
+func (s *RemoteKeySet) Refresh() error {
+	now := time.Now()
+
+	s.mu.Lock()
+	s.fetched = now
+	s.mu.Unlock()
+
+	return s.refresh(now)
+}
+
+// refresh fetches the key set without holding the lock, so that keys already cached can still be looked up
+// meanwhile, and then replaces the cached keys unless a later fetch already did.
+func (s *RemoteKeySet) refresh(now time.Time) error {
+	keys, ttl, err := s.fetch()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Before(s.updated) {
+		return nil
+	}
+	s.keys = keys
+	s.updated = now
+	s.expires = now.Add(ttl)
+	return nil
+}
+
+// fetch returns the keys of the key set, and how long they may be cached
+func (s *RemoteKeySet) fetch() (map[string]crypto.PublicKey, time.Duration, error) {
+	res, err := s.options.HTTPClient.Get(s.url)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch key set %s: %s", s.url, err)
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(res.Body, jwksMaxLength))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch key set %s: %s", s.url, err)
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("failed to fetch key set %s: status %d", s.url, res.StatusCode)
+	}
+
+	var jwks JSONWebKeySet
+	if err := json.Unmarshal(body, &jwks); err != nil {
+		return nil, 0, fmt.Errorf("invalid key set %s: %s", s.url, err)
+	}
+	keys, err := jwks.keys()
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid key set %s: %s", s.url, err)
+	}
+	return keys, cacheTTL(res.Header, s.options.CacheTTL), nil
+}
+
+// cacheTTL returns the max-age of a Cache-Control header, or def if there is none
+func cacheTTL(header http.Header, def time.Duration) time.Duration {
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		directive = strings.TrimSpace(directive)
+		if !strings.HasPrefix(directive, "max-age=") {
+			continue
+		}
+		secs, err := strconv.Atoi(strings.TrimPrefix(directive, "max-age="))
+		if err == nil && secs >= 0 {
+			return time.Duration(secs) * time.Second
+		}
+	}
+	return def
+}
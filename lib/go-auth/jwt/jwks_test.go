@@ -0,0 +1,393 @@
+package jwt_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/docker/licensing/lib/go-auth/jwt"
+
+	"github.com/stretchr/testify/require"
+)
+
+func defaultPublicKey(t *testing.T) crypto.PublicKey {
+	block, _ := pem.Decode(load(t, "testdata/private-key"))
+	require.NotNil(t, block)
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	require.NoError(t, err)
+	return key.Public()
+}
+
+func keySetJSON(t *testing.T, keys map[string]crypto.PublicKey) []byte {
+	var jwks jwt.JSONWebKeySet
+	for keyID, key := range keys {
+		jwk, err := jwt.NewJSONWebKey(keyID, key)
+		require.NoError(t, err)
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+	b, err := json.Marshal(jwks)
+	require.NoError(t, err)
+	return b
+}
+
+func encodeWithKeyID(t *testing.T, signingKey []byte, keyID string) string {
+	tokenStr, err := jwt.Encode(defaultIdentity(), jwt.EncodeOptions{
+		SigningKey: signingKey,
+		KeyID:      keyID,
+		Expiration: time.Now().Add(time.Hour).Unix(),
+	})
+	require.NoError(t, err)
+	return tokenStr
+}
+
+func TestStaticKeySet(t *testing.T) {
+	t.Parallel()
+
+	keySet, err := jwt.ParseKeySet(keySetJSON(t, map[string]crypto.PublicKey{
+		"key-1": defaultPublicKey(t),
+	}))
+	require.NoError(t, err)
+
+	privateKey := load(t, "testdata/private-key")
+
+	decoded, err := jwt.Decode(encodeWithKeyID(t, privateKey, "key-1"), jwt.DecodeOptions{
+		KeySet: keySet,
+	})
+	require.NoError(t, err)
+	require.Equal(t, defaultIdentity().DockerID, decoded.DockerID)
+
+	// unknown keys are rejected when the token has no x5c to fall back to
+	_, err = jwt.Decode(encodeWithKeyID(t, privateKey, "key-2"), jwt.DecodeOptions{
+		KeySet: keySet,
+	})
+	require.Error(t, err)
+	require.Regexp(t, "key not found", err)
+
+	// the key must be the one the token was signed with
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	otherPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(other)})
+
+	_, err = jwt.Decode(encodeWithKeyID(t, otherPEM, "key-1"), jwt.DecodeOptions{
+		KeySet: keySet,
+	})
+	require.Error(t, err)
+	require.Regexp(t, "token signature error", err)
+}
+
+func TestKeySetFallsBackToX5c(t *testing.T) {
+	t.Parallel()
+
+	keySet, err := jwt.ParseKeySet(keySetJSON(t, map[string]crypto.PublicKey{}))
+	require.NoError(t, err)
+
+	options := jwt.DecodeOptions{
+		CertificateChain: defaultRootCertChain(t),
+		KeySet:           keySet,
+	}
+
+	// no kid
+	tokenStr, err := jwt.Encode(defaultIdentity(), jwt.EncodeOptions{
+		SigningKey:  load(t, "testdata/private-key"),
+		Certificate: load(t, "testdata/trusted-cert"),
+		Expiration:  time.Now().Add(time.Hour).Unix(),
+	})
+	require.NoError(t, err)
+	_, err = jwt.Decode(tokenStr, options)
+	require.NoError(t, err)
+
+	// kid missing from the key set
+	tokenStr, err = jwt.Encode(defaultIdentity(), jwt.EncodeOptions{
+		SigningKey:  load(t, "testdata/private-key"),
+		Certificate: load(t, "testdata/trusted-cert"),
+		KeyID:       "unknown",
+		Expiration:  time.Now().Add(time.Hour).Unix(),
+	})
+	require.NoError(t, err)
+	_, err = jwt.Decode(tokenStr, options)
+	require.NoError(t, err)
+
+	// the x5c certificate must still be trusted
+	tokenStr, err = jwt.Encode(defaultIdentity(), jwt.EncodeOptions{
+		SigningKey:  load(t, "testdata/private-key"),
+		Certificate: load(t, "testdata/untrusted-cert"),
+		KeyID:       "unknown",
+		Expiration:  time.Now().Add(time.Hour).Unix(),
+	})
+	require.NoError(t, err)
+	_, err = jwt.Decode(tokenStr, options)
+	require.Error(t, err)
+	require.Regexp(t, "certificate signed by unknown authority", err)
+}
+
+func TestKeySetWithoutCertificateChainIgnoresX5c(t *testing.T) {
+	t.Parallel()
+
+	keySet, err := jwt.ParseKeySet(keySetJSON(t, map[string]crypto.PublicKey{}))
+	require.NoError(t, err)
+
+	// with no certificate chain the x5c certificate would be validated against the system roots
+	options := jwt.DecodeOptions{
+		KeySet: keySet,
+	}
+
+	for _, keyID := range []string{"", "unknown"} {
+		tokenStr, err := jwt.Encode(defaultIdentity(), jwt.EncodeOptions{
+			SigningKey:  load(t, "testdata/private-key"),
+			Certificate: load(t, "testdata/trusted-cert"),
+			KeyID:       keyID,
+			Expiration:  time.Now().Add(time.Hour).Unix(),
+		})
+		require.NoError(t, err)
+
+		_, err = jwt.Decode(tokenStr, options)
+		require.Error(t, err)
+		require.Regexp(t, "key not found", err)
+
+		_, err = jwt.IsExpired(tokenStr, options)
+		require.Error(t, err)
+		require.Regexp(t, "key not found", err)
+
+		inspection := jwt.Inspect(tokenStr, options)
+		require.False(t, inspection.Valid)
+		check, ok := inspection.Check(jwt.CheckSignature)
+		require.True(t, ok)
+		require.False(t, check.Passed)
+	}
+}
+
+type jwksServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	jwks     []byte
+	requests int
+}
+
+func newJWKSServer(jwks []byte) *jwksServer {
+	s := &jwksServer{jwks: jwks}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(s.jwks)
+	}))
+	return s
+}
+
+func (s *jwksServer) set(jwks []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jwks = jwks
+}
+
+func (s *jwksServer) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+func TestRemoteKeySet(t *testing.T) {
+	t.Parallel()
+
+	privateKey := load(t, "testdata/private-key")
+	publicKey := defaultPublicKey(t)
+
+	server := newJWKSServer(keySetJSON(t, map[string]crypto.PublicKey{
+		"key-1": publicKey,
+	}))
+	defer server.Close()
+
+	keySet := jwt.NewRemoteKeySet(server.URL, jwt.RemoteKeySetOptions{
+		MinRefreshInterval: time.Nanosecond,
+	})
+	options := jwt.DecodeOptions{KeySet: keySet}
+
+	// the key set is fetched once and cached
+	for i := 0; i < 3; i++ {
+		_, err := jwt.Decode(encodeWithKeyID(t, privateKey, "key-1"), options)
+		require.NoError(t, err)
+	}
+	require.Equal(t, 1, server.count())
+
+	// rotated keys are picked up by fetching the key set again
+	rotated, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	rotatedPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rotated)})
+	server.set(keySetJSON(t, map[string]crypto.PublicKey{
+		"key-1": publicKey,
+		"key-2": rotated.Public(),
+	}))
+
+	_, err = jwt.Decode(encodeWithKeyID(t, rotatedPEM, "key-2"), options)
+	require.NoError(t, err)
+	require.Equal(t, 2, server.count())
+
+	_, err = jwt.Decode(encodeWithKeyID(t, privateKey, "key-3"), options)
+	require.Regexp(t, "key not found", err)
+	require.Equal(t, 3, server.count())
+}
+
+func TestRemoteKeySetRefreshLimit(t *testing.T) {
+	t.Parallel()
+
+	server := newJWKSServer(keySetJSON(t, map[string]crypto.PublicKey{
+		"key-1": defaultPublicKey(t),
+	}))
+	defer server.Close()
+
+	keySet := jwt.NewRemoteKeySet(server.URL, jwt.RemoteKeySetOptions{})
+
+	_, err := keySet.Key("key-1")
+	require.NoError(t, err)
+
+	// unknown keys do not cause the key set to be fetched on every token
+	for i := 0; i < 3; i++ {
+		_, err = keySet.Key("unknown")
+		require.True(t, errors.Is(err, jwt.ErrKeyNotFound))
+	}
+	require.Equal(t, 1, server.count())
+
+	require.NoError(t, keySet.Refresh())
+	require.Equal(t, 2, server.count())
+}
+
+func TestRemoteKeySetLookupDuringRefresh(t *testing.T) {
+	t.Parallel()
+
+	jwks := keySetJSON(t, map[string]crypto.PublicKey{
+		"key-1": defaultPublicKey(t),
+	})
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) > 1 {
+			close(started)
+			<-release
+		}
+		w.Write(jwks)
+	}))
+	defer server.Close()
+
+	keySet := jwt.NewRemoteKeySet(server.URL, jwt.RemoteKeySetOptions{
+		MinRefreshInterval: time.Nanosecond,
+	})
+
+	_, err := keySet.Key("key-1")
+	require.NoError(t, err)
+
+	// an unknown key causes the key set to be fetched again, which hangs
+	done := make(chan error)
+	go func() {
+		_, err := keySet.Key("unknown")
+		done <- err
+	}()
+	<-started
+
+	// cached keys are still looked up while the key set is being fetched
+	lookup := make(chan error)
+	go func() {
+		_, err := keySet.Key("key-1")
+		lookup <- err
+	}()
+	select {
+	case err := <-lookup:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("key lookup blocked by the key set refresh")
+	}
+
+	close(release)
+	require.True(t, errors.Is(<-done, jwt.ErrKeyNotFound))
+}
+
+func TestRemoteKeySetCacheControl(t *testing.T) {
+	t.Parallel()
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests > 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Cache-Control", "public, max-age=0")
+		w.Write(keySetJSON(t, map[string]crypto.PublicKey{
+			"key-1": defaultPublicKey(t),
+		}))
+	}))
+	defer server.Close()
+
+	keySet := jwt.NewRemoteKeySet(server.URL, jwt.RemoteKeySetOptions{
+		MinRefreshInterval: time.Nanosecond,
+	})
+
+	_, err := keySet.Key("key-1")
+	require.NoError(t, err)
+
+	// the expired key set is fetched again, and its keys kept while the endpoint fails
+	_, err = keySet.Key("key-1")
+	require.NoError(t, err)
+	require.Equal(t, 2, requests)
+
+	require.Error(t, keySet.Refresh())
+}
+
+func TestJSONWebKeyTypes(t *testing.T) {
+	t.Parallel()
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+	edKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	for _, key := range []crypto.PublicKey{defaultPublicKey(t), &ecKey.PublicKey, edKey} {
+		jwk, err := jwt.NewJSONWebKey("key", key)
+		require.NoError(t, err)
+
+		parsed, err := jwk.PublicKey()
+		require.NoError(t, err)
+		require.True(t, parsed.(interface{ Equal(crypto.PublicKey) bool }).Equal(key))
+	}
+
+	_, err = jwt.JSONWebKey{KeyType: "EC", Curve: "P-256", X: "AQ", Y: "AQ"}.PublicKey()
+	require.Error(t, err)
+
+	_, err = jwt.JSONWebKey{KeyType: "oct"}.PublicKey()
+	require.Error(t, err)
+}
+
+func TestKeySetSkipsUnsupportedKeys(t *testing.T) {
+	t.Parallel()
+
+	jwk, err := jwt.NewJSONWebKey("key-1", defaultPublicKey(t))
+	require.NoError(t, err)
+	unsupported := jwt.JSONWebKey{KeyType: "oct", KeyID: "key-2"}
+
+	keySet, err := jwt.NewStaticKeySet(&jwt.JSONWebKeySet{Keys: []jwt.JSONWebKey{unsupported, jwk}})
+	require.NoError(t, err)
+
+	_, err = keySet.Key("key-1")
+	require.NoError(t, err)
+	_, err = keySet.Key("key-2")
+	require.True(t, errors.Is(err, jwt.ErrKeyNotFound))
+
+	// a key set with no usable key is an error
+	_, err = jwt.NewStaticKeySet(&jwt.JSONWebKeySet{Keys: []jwt.JSONWebKey{unsupported}})
+	require.Error(t, err)
+}
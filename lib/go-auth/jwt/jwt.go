@@ -1,9 +1,16 @@
 package jwt
 
 import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
 	"crypto/x509"
 	"encoding/base64"
+	"encoding/json"
 	"encoding/pem"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -24,6 +31,10 @@ const (
 	// non standard email claim
 	email = "email"
 
+	// full name claim
+	// https://openid.net/specs/openid-connect-core-1_0.html#StandardClaims
+	fullName = "name"
+
 	// subject claim
 	// https://tools.ietf.org/html/rfc7519#section-4.1.2
 	sub = "sub"
@@ -40,28 +51,78 @@ const (
 	// https://tools.ietf.org/html/rfc7519#section-4.1.4
 	exp = "exp"
 
+	// not before claim
+	// https://tools.ietf.org/html/rfc7519#section-4.1.5
+	nbf = "nbf"
+
+	// issuer claim
+	// https://tools.ietf.org/html/rfc7519#section-4.1.1
+	iss = "iss"
+
+	// audience claim
+	// https://tools.ietf.org/html/rfc7519#section-4.1.3
+	aud = "aud"
+
 	// Legacy claims the gateways are still using to validate a JWT.
 	sessionid = "session_id" // same as a `jti`
 	userid    = "user_id"    // same as a `sub`
 
 	// non standard scope claim
 	scope = "scope"
+
+	// actor claim, identifying the party acting on behalf of the subject
+	// https://tools.ietf.org/html/rfc8693#section-4.1
+	act = "act"
+
+	// nested act claims are decoded up to this depth
+	maxActorDepth = 8
+)
+
+// reservedClaims are the claims set from the identity and encoding options, which custom claims cannot override
+var reservedClaims = []string{username, email, fullName, sub, jti, iat, exp, nbf, iss, aud, sessionid, userid, scope, act}
+
+// Signing algorithms
+const (
+	RS256 = "RS256"
+	RS384 = "RS384"
+	RS512 = "RS512"
+	PS256 = "PS256"
+	ES256 = "ES256"
+	ES384 = "ES384"
+	EdDSA = "EdDSA" // Ed25519 only
 )
 
+// DefaultAlgorithms are the signing algorithms accepted by Decode unless DecodeOptions.Algorithms is set
+var DefaultAlgorithms = []string{RS256, RS384, RS512, PS256, ES256, ES384, EdDSA}
+
 // EncodeOptions holds JWT encoding options
 type EncodeOptions struct {
 	// The token expiration time, represented as a UNIX timestamp
 	Expiration int64
 
-	// TODO would be good to add a leeway option, but go-jwt
-	// does not support this. see: https://github.com/dgrijalva/jwt-go/issues/131
+	// The time before which the token must not be accepted, represented as a UNIX timestamp. Omitted if zero.
+	NotBefore int64
 
-	// The private key with which to sign the token
+	// The issuer of the token. Omitted if empty.
+	Issuer string
+
+	// The recipients the token is intended for. Omitted if empty.
+	Audience []string
+
+	// The PEM encoded private key with which to sign the token, see ParsePrivateKey
 	SigningKey []byte
 
-	// The x509 certificate associated with the signing key
+	// The signing algorithm, one of RS256, RS384, RS512, PS256, ES256, ES384 or EdDSA. Defaults to RS256.
+	// The key type must match the algorithm.
+	Algorithm string
+
+	// The PEM encoded x509 certificate associated with the signing key, included in the x5c header.
+	// It may be followed by the intermediate certificates of its chain. May be omitted if KeyID is set.
 	Certificate []byte
 
+	// The id of the signing key in the key set of the verifying party, included in the kid header
+	KeyID string
+
 	// Identifier for the JWT. If this is empty, a random UUID will be generated.
 	Jti string
 
@@ -71,20 +132,47 @@ type EncodeOptions struct {
 
 // Encode creates a JWT string for the given identity.DockerIdentity.
 func Encode(identity identity.DockerIdentity, options EncodeOptions) (string, error) {
-	block, _ := pem.Decode(options.Certificate)
-	if block == nil {
-		return "", fmt.Errorf("invalid key: failed to parse header")
+	alg := options.Algorithm
+	if alg == "" {
+		alg = RS256
+	}
+	if !contains(DefaultAlgorithms, alg) {
+		return "", fmt.Errorf("unsupported signing algorithm %q", alg)
+	}
+	method := jwt.GetSigningMethod(alg)
+
+	var x5cCerts []string
+	if len(options.Certificate) > 0 || options.KeyID == "" {
+		block, rest := pem.Decode(options.Certificate)
+		if block == nil {
+			return "", fmt.Errorf("invalid key: failed to parse header")
+		}
+
+		for block != nil {
+			encodedCert := base64.StdEncoding.EncodeToString(block.Bytes)
+			x5cCerts = append(x5cCerts, encodedCert)
+			block, rest = pem.Decode(rest)
+		}
 	}
 
-	encodedCert := base64.StdEncoding.EncodeToString(block.Bytes)
-	x5cCerts := [1]string{encodedCert}
+	// custom claims, set first so that they cannot override the claims below
+	claims := make(map[string]interface{})
+	for name, value := range identity.Claims {
+		if contains(reservedClaims, name) {
+			return "", fmt.Errorf("custom claim %q is reserved", name)
+		}
+		claims[name] = value
+	}
 
 	// non standard fields
 	// Note: this is a required field
-	claims := make(map[string]interface{})
 	claims[username] = identity.Username
 	claims[email] = identity.Email
 
+	if identity.FullName != "" {
+		claims[fullName] = identity.FullName
+	}
+
 	// standard JWT fields, consult the JWT spec for details
 	claims[sub] = identity.DockerID
 
@@ -92,6 +180,15 @@ func Encode(identity identity.DockerIdentity, options EncodeOptions) (string, er
 		claims[scope] = strings.Join(identity.Scopes, " ")
 	}
 
+	if identity.Actor != nil {
+		for _, subject := range identity.DelegationChain() {
+			if subject == "" {
+				return "", fmt.Errorf("actor subject is required")
+			}
+		}
+		claims[act] = actorClaim(identity.Actor)
+	}
+
 	jtiStr := options.Jti
 	if len(jtiStr) == 0 {
 		jtiStr = "jti-" + uuid.New().String()
@@ -101,84 +198,195 @@ func Encode(identity identity.DockerIdentity, options EncodeOptions) (string, er
 	claims[iat] = time.Now().Unix()
 	claims[exp] = options.Expiration
 
+	if options.NotBefore != 0 {
+		claims[nbf] = options.NotBefore
+	}
+
+	if options.Issuer != "" {
+		claims[iss] = options.Issuer
+	}
+
+	// a single audience is encoded as a string, as most verifiers expect
+	switch len(options.Audience) {
+	case 0:
+	case 1:
+		claims[aud] = options.Audience[0]
+	default:
+		claims[aud] = options.Audience
+	}
+
 	if options.IncludeLegacyClaims {
 		claims[sessionid] = jtiStr
 		claims[userid] = identity.DockerID
 	}
 
-	// Note: we only support a RS256 signing method right now. If we want to support
-	// additional signing methods (for example, HS256), this could be specified as an
-	// encoding option.
-	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims(claims))
-	token.Header[x5c] = x5cCerts
+	token := jwt.NewWithClaims(method, jwt.MapClaims(claims))
+	if x5cCerts != nil {
+		token.Header[x5c] = x5cCerts
+	}
+	if options.KeyID != "" {
+		token.Header[kid] = options.KeyID
+	}
 
-	privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(options.SigningKey)
+	privateKey, err := ParsePrivateKey(options.SigningKey)
 	if err != nil {
 		return "", err
 	}
+	if err := checkSigningMethod(method, privateKey.Public()); err != nil {
+		return "", fmt.Errorf("invalid key: %T cannot be used with %s", privateKey, alg)
+	}
 	return token.SignedString(privateKey)
 }
 
 // DecodeOptions holds JWT decoding options
 type DecodeOptions struct {
+	// CertificateChain holds the root certificates against which the x5c header certificate is validated
 	CertificateChain *x509.CertPool
+
+	// KeySet holds the keys with which tokens with a kid header are verified. Tokens with no kid header, or
+	// a kid not found in the key set, are verified with their x5c header certificate if they have one and
+	// CertificateChain is set, and are otherwise rejected.
+	KeySet KeySet
+
+	// Issuers, if set, are the accepted token issuers. Tokens with any other issuer, or none, are rejected.
+	Issuers []string
+
+	// Audiences, if set, are the audiences of which tokens must name at least one.
+	Audiences []string
+
+	// Leeway is the clock skew tolerated when validating the exp, nbf and iat claims
+	Leeway time.Duration
+
+	// RequiredClaims are claims tokens are rejected without, in addition to the username and sub claims
+	RequiredClaims []string
+
+	// Algorithms are the accepted signing algorithms, defaults to DefaultAlgorithms. Tokens signed with any
+	// other algorithm are rejected before their signature is verified.
+	Algorithms []string
+
+	// RevocationChecker, if set, rejects tokens whose jti or legacy session_id claim has been revoked
+	RevocationChecker RevocationChecker
+
+	// Certificates holds x5c certificate chain validation options
+	Certificates CertificateOptions
 }
 
 // Decode decodes the given JWT string, returning the decoded identity.DockerIdentity
 func Decode(tokenStr string, options DecodeOptions) (*identity.DockerIdentity, error) {
-	token, err := jwt.Parse(tokenStr, keyFunc(options.CertificateChain))
-
+	token, err := parse(tokenStr, options)
 	if err != nil {
-		if ve, ok := err.(*jwt.ValidationError); ok {
-			return nil, &ValidationError{VError: ve}
-		}
-		return nil, fmt.Errorf("error decoding token: %s", err)
+		return nil, err
 	}
 
 	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		username, ok := claims[username].(string)
+		return identityFromClaims(claims)
+	}
+
+	// no error but an invalid token seems like a corner case, but just to be sure
+	return nil, fmt.Errorf("token was invalid")
+}
+
+// identityFromClaims returns the identity the token claims are about
+func identityFromClaims(claims jwt.MapClaims) (*identity.DockerIdentity, error) {
+	usernameClaim, ok := claims[username].(string)
+	if !ok {
+		return nil, fmt.Errorf("%v claim not present", username)
+	}
+	dockerID, ok := claims[sub].(string)
+	if !ok {
+		return nil, fmt.Errorf("%v claim not present", sub)
+	}
+
+	// email and full name are optional
+	emailClaim, _ := claims[email].(string)
+	fullNameClaim, _ := claims[fullName].(string)
+
+	var scopes []string
+	if scopeClaim, ok := claims[scope]; ok {
+		sstr, ok := scopeClaim.(string)
 		if !ok {
-			return nil, fmt.Errorf("%v claim not present", username)
+			return nil, fmt.Errorf("scope claim invalid")
 		}
-		dockerID, ok := claims[sub].(string)
-		if !ok {
-			return nil, fmt.Errorf("%v claim not present", sub)
+		scopes = strings.Split(sstr, " ")
+	}
+
+	var actor *identity.Actor
+	if actClaim, ok := claims[act]; ok {
+		var err error
+		if actor, err = parseActor(actClaim, 1); err != nil {
+			return nil, err
 		}
+	}
 
-		// email is optional
-		email, _ := claims[email].(string)
+	return &identity.DockerIdentity{
+		Username: usernameClaim,
+		DockerID: dockerID,
+		FullName: fullNameClaim,
+		Email:    emailClaim,
+		Scopes:   scopes,
+		Claims:   customClaims(claims),
+		Actor:    actor,
+	}, nil
+}
 
-		var scopes []string
-		if scopeClaim, ok := claims[scope]; ok {
-			sstr, ok := scopeClaim.(string)
-			if !ok {
-				return nil, fmt.Errorf("scope claim invalid")
-			}
-			scopes = strings.Split(sstr, " ")
-		}
+// actorClaim returns the act claim of the actor, nesting the claims of prior actors
+func actorClaim(actor *identity.Actor) map[string]interface{} {
+	claim := map[string]interface{}{sub: actor.Subject}
+	if actor.Actor != nil {
+		claim[act] = actorClaim(actor.Actor)
+	}
+	return claim
+}
 
-		return &identity.DockerIdentity{
-			Username: username,
-			DockerID: dockerID,
-			Email:    email,
-			Scopes:   scopes,
-		}, nil
+// parseActor parses an act claim, at the given nesting depth
+func parseActor(claim interface{}, depth int) (*identity.Actor, error) {
+	if depth > maxActorDepth {
+		return nil, fmt.Errorf("act claim invalid: more than %d nested actors", maxActorDepth)
 	}
 
-	// no error but an invalid token seems like a corner case, but just to be sure
-	return nil, fmt.Errorf("token was invalid")
+	m, ok := claim.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("act claim invalid")
+	}
+	subject, ok := m[sub].(string)
+	if !ok || subject == "" {
+		return nil, fmt.Errorf("act claim invalid: %v claim not present", sub)
+	}
+
+	actor := &identity.Actor{Subject: subject}
+	if prior, ok := m[act]; ok {
+		var err error
+		if actor.Actor, err = parseActor(prior, depth+1); err != nil {
+			return nil, err
+		}
+	}
+	return actor, nil
+}
+
+// customClaims returns the claims which are not reserved, or nil if there are none
+func customClaims(claims jwt.MapClaims) map[string]interface{} {
+	var custom map[string]interface{}
+	for name, value := range claims {
+		if contains(reservedClaims, name) {
+			continue
+		}
+		if custom == nil {
+			custom = make(map[string]interface{})
+		}
+		custom[name] = value
+	}
+	return custom
 }
 
 // IsExpired returns true if the token has expired, false otherwise
 func IsExpired(tokenStr string, options DecodeOptions) (bool, error) {
-	rootCerts := options.CertificateChain
-	_, err := jwt.Parse(tokenStr, keyFunc(rootCerts))
+	_, err := parse(tokenStr, options)
 	if err == nil {
 		return false, nil
 	}
 
-	if ve, ok := err.(*jwt.ValidationError); ok {
-		if ve.Errors&(jwt.ValidationErrorExpired) != 0 {
+	if ve, ok := err.(*ValidationError); ok {
+		if ve.VError.Errors&(jwt.ValidationErrorExpired) != 0 {
 			return true, nil
 		}
 
@@ -188,73 +396,414 @@ func IsExpired(tokenStr string, options DecodeOptions) (bool, error) {
 	return false, err
 }
 
-// keyFunc returns the jwt.KeyFunc with which to validate the token
-func keyFunc(roots *x509.CertPool) jwt.Keyfunc {
-	return func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+// parse verifies the token signature and validates its claims
+func parse(tokenStr string, options DecodeOptions) (*jwt.Token, error) {
+	// claims are validated below, as the parser does not support leeway
+	// see: https://github.com/dgrijalva/jwt-go/issues/131
+	parser := &jwt.Parser{
+		ValidMethods:         options.Algorithms,
+		SkipClaimsValidation: true,
+	}
+	if len(parser.ValidMethods) == 0 {
+		parser.ValidMethods = DefaultAlgorithms
+	}
+
+	token, err := parser.Parse(tokenStr, keyFunc(options))
+	if err != nil {
+		if ve, ok := err.(*jwt.ValidationError); ok {
+			return nil, newValidationError(ve, "")
 		}
+		return nil, fmt.Errorf("error decoding token: %s", err)
+	}
 
-		// x5c holds a base64 encoded DER encoded x509 certificate
-		// associated with the private key used to sign the token.
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, fmt.Errorf("token was invalid")
+	}
 
-		// For more information, see:
-		// https://tools.ietf.org/html/draft-ietf-jose-json-web-key-41#page-9
-		// https://tools.ietf.org/html/draft-ietf-jose-json-web-key-41#appendix-B
-		x5c, ok := token.Header[x5c].([]interface{})
-		if !ok {
-			return nil, fmt.Errorf("x5c token header not present")
+	if err := validateClaims(claims, options); err != nil {
+		return nil, err
+	}
+
+	if options.RevocationChecker != nil {
+		if err := checkRevocation(claims, options.RevocationChecker); err != nil {
+			return nil, err
 		}
+	}
+
+	return token, nil
+}
+
+// validateClaims validates the time, issuer and audience claims, and checks required claims are present.
+// All claims are validated, and the returned error flags each failure.
+func validateClaims(claims jwt.MapClaims, options DecodeOptions) *ValidationError {
+	failures := checkClaims(claims, options)
+	if len(failures) == 0 {
+		return nil
+	}
+
+	var flags uint32
+	for _, f := range failures {
+		flags |= f.flag
+	}
+	return newValidationError(jwt.NewValidationError(failures[0].message, flags), failures[0].claim)
+}
+
+// claimFailure describes a claim which failed validation
+type claimFailure struct {
+	check   CheckName
+	flag    uint32
+	claim   string
+	message string
+}
+
+// checkClaims returns the claims failing validation
+func checkClaims(claims jwt.MapClaims, options DecodeOptions) []claimFailure {
+	var failures []claimFailure
+	fail := func(check CheckName, flag uint32, name, format string, args ...interface{}) {
+		failures = append(failures, claimFailure{
+			check:   check,
+			flag:    flag,
+			claim:   name,
+			message: fmt.Sprintf(format, args...),
+		})
+	}
 
-		if len(x5c) == 0 {
-			return nil, fmt.Errorf("x5c token header was empty")
+	now := jwt.TimeFunc()
+	leeway := options.Leeway
+
+	timeChecks := map[string]CheckName{exp: CheckExpiry, nbf: CheckNotBefore, iat: CheckIssuedAt}
+	for _, name := range []string{exp, nbf, iat} {
+		check := timeChecks[name]
+		t, ok, err := timeClaim(claims, name)
+		if err != nil {
+			fail(check, jwt.ValidationErrorClaimsInvalid, name, "%v", err)
+			continue
+		}
+		// zero times are treated as absent, as by jwt.MapClaims
+		if !ok || t.Unix() == 0 {
+			continue
 		}
 
-		x5cString, ok := x5c[0].(string)
-		if !ok {
-			return nil, fmt.Errorf("x5c token header was not a string")
+		switch {
+		case name == exp && now.After(t.Add(leeway)):
+			fail(check, jwt.ValidationErrorExpired, name, "token is expired by %v", now.Sub(t))
+		case name == nbf && now.Add(leeway).Before(t):
+			fail(check, jwt.ValidationErrorNotValidYet, name, "token is not valid yet")
+		case name == iat && now.Add(leeway).Before(t):
+			fail(check, jwt.ValidationErrorIssuedAt, name, "token used before issued")
 		}
+	}
 
-		decodedCert, err := base64.StdEncoding.DecodeString(x5cString)
+	if len(options.Issuers) > 0 {
+		issuer, _ := claims[iss].(string)
+		if !contains(options.Issuers, issuer) {
+			fail(CheckIssuer, jwt.ValidationErrorIssuer, iss, "unexpected issuer %q", issuer)
+		}
+	}
+
+	if len(options.Audiences) > 0 {
+		audiences, err := audienceClaim(claims)
 		if err != nil {
-			return nil, err
+			fail(CheckAudience, jwt.ValidationErrorAudience, aud, "%v", err)
+		} else if !containsAny(options.Audiences, audiences) {
+			fail(CheckAudience, jwt.ValidationErrorAudience, aud, "unexpected audience %q", audiences)
+		}
+	}
+
+	for _, name := range options.RequiredClaims {
+		if _, ok := claims[name]; !ok {
+			fail(CheckRequiredClaims, jwt.ValidationErrorClaimsInvalid, name, "%v claim not present", name)
 		}
+	}
+
+	return failures
+}
 
-		cert, err := validateCert(decodedCert, roots)
+// timeClaim returns the time of a NumericDate claim, and whether the claim is present
+func timeClaim(claims jwt.MapClaims, name string) (time.Time, bool, error) {
+	value, ok := claims[name]
+	if !ok {
+		return time.Time{}, false, nil
+	}
+
+	var secs int64
+	switch v := value.(type) {
+	case float64:
+		secs = int64(v)
+	case json.Number:
+		n, err := v.Int64()
 		if err != nil {
-			return nil, err
+			return time.Time{}, false, fmt.Errorf("%v claim invalid", name)
 		}
+		secs = n
+	default:
+		return time.Time{}, false, fmt.Errorf("%v claim invalid", name)
+	}
 
-		key := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	return time.Unix(secs, 0), true, nil
+}
+
+// audienceClaim returns the audiences of the aud claim, which may be a string or an array of strings
+func audienceClaim(claims jwt.MapClaims) ([]string, error) {
+	switch v := claims[aud].(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{v}, nil
+	case []interface{}:
+		audiences := make([]string, 0, len(v))
+		for _, a := range v {
+			s, ok := a.(string)
+			if !ok {
+				return nil, fmt.Errorf("%v claim invalid", aud)
+			}
+			audiences = append(audiences, s)
+		}
+		return audiences, nil
+	}
+	return nil, fmt.Errorf("%v claim invalid", aud)
+}
 
-		return jwt.ParseRSAPublicKeyFromPEM(key)
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
 	}
+	return false
 }
 
-// validateCert validates the ASN.1 DER encoded cert using the given x509.CertPool root
-// certificate chain. If valid, the parsed x509.Certificate is returned.
-func validateCert(derData []byte, roots *x509.CertPool) (*x509.Certificate, error) {
-	opts := x509.VerifyOptions{
-		Roots: roots,
+func containsAny(values []string, candidates []string) bool {
+	for _, c := range candidates {
+		if contains(values, c) {
+			return true
+		}
 	}
+	return false
+}
 
-	cert, err := x509.ParseCertificate(derData)
+// ExpiresAt returns the expiration time of the token, or the zero time if it has none. The token is NOT
+// verified: this is meant for clients scheduling the refresh of their own tokens, use Decode or IsExpired
+// to validate a token.
+func ExpiresAt(tokenStr string) (time.Time, error) {
+	claims, err := unverifiedClaims(tokenStr)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse certificate: [%v]", err)
+		return time.Time{}, err
+	}
+
+	// exp 0, as Encode writes when no expiration is set, means the token does not expire
+	expiresAt, ok, err := timeClaim(claims, exp)
+	if err != nil || !ok || expiresAt.Unix() <= 0 {
+		return time.Time{}, err
+	}
+
+	return expiresAt, nil
+}
+
+// unverifiedClaims decodes the claims of the token without verifying it
+func unverifiedClaims(tokenStr string) (jwt.MapClaims, error) {
+	parts := strings.Split(tokenStr, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed token: expected 3 segments, got %d", len(parts))
 	}
 
-	_, err = cert.Verify(opts)
+	payload, err := jwt.DecodeSegment(parts[1])
 	if err != nil {
-		return nil, fmt.Errorf("failed to verify certificate: [%v]", err)
+		return nil, fmt.Errorf("malformed token: %s", err)
+	}
+
+	claims := jwt.MapClaims{}
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.UseNumber()
+	if err := dec.Decode(&claims); err != nil {
+		return nil, fmt.Errorf("malformed token: %s", err)
 	}
 
-	return cert, nil
+	return claims, nil
+}
+
+// keyFunc returns the jwt.KeyFunc with which to validate the token
+func keyFunc(options DecodeOptions) jwt.Keyfunc {
+	return func(token *jwt.Token) (interface{}, error) {
+		keyID, _ := token.Header[kid].(string)
+		_, hasX5c := token.Header[x5c]
+
+		if keyID != "" && options.KeySet != nil {
+			key, err := options.KeySet.Key(keyID)
+			if err == nil {
+				if err := checkSigningMethod(token.Method, key); err != nil {
+					return nil, err
+				}
+				return key, nil
+			}
+
+			// fall back to the x5c certificate of tokens signed with keys missing from the key set
+			if !errors.Is(err, ErrKeyNotFound) || !hasX5c {
+				return nil, err
+			}
+		}
+
+		// with a key set but no certificate chain, the x5c certificate would be validated against the
+		// system roots, so any publicly trusted certificate could sign tokens
+		if options.KeySet != nil && options.CertificateChain == nil {
+			return nil, ErrKeyNotFound
+		}
+
+		return x5cKey(token, options)
+	}
+}
+
+// checkSigningMethod returns an error if the token signing method cannot be used with the key
+func checkSigningMethod(method jwt.SigningMethod, key crypto.PublicKey) error {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		switch method.(type) {
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+			return nil
+		}
+	case *ecdsa.PublicKey:
+		// the curve must match the algorithm, e.g. P-256 for ES256
+		if m, ok := method.(*jwt.SigningMethodECDSA); ok && m.CurveBits == k.Curve.Params().BitSize {
+			return nil
+		}
+	case ed25519.PublicKey:
+		if _, ok := method.(*SigningMethodEd25519); ok {
+			return nil
+		}
+	}
+	return fmt.Errorf("unexpected signing method: %v", method.Alg())
+}
+
+// x5cKey returns the public key of the token x5c header certificate, once its chain is validated
+func x5cKey(token *jwt.Token, options DecodeOptions) (interface{}, error) {
+	// x5c holds the base64 encoded DER encoded x509 certificate associated
+	// with the private key used to sign the token, optionally followed by
+	// the intermediate certificates of its chain.
+
+	// For more information, see:
+	// https://tools.ietf.org/html/draft-ietf-jose-json-web-key-41#page-9
+	// https://tools.ietf.org/html/draft-ietf-jose-json-web-key-41#appendix-B
+	x5c, ok := token.Header[x5c].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("x5c token header not present")
+	}
+
+	if len(x5c) == 0 {
+		return nil, fmt.Errorf("x5c token header was empty")
+	}
+
+	chain := make([]*x509.Certificate, len(x5c))
+	for i := range x5c {
+		x5cString, ok := x5c[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("x5c token header was not a string")
+		}
+
+		decodedCert, err := base64.StdEncoding.DecodeString(x5cString)
+		if err != nil {
+			return nil, err
+		}
+
+		chain[i], err = x509.ParseCertificate(decodedCert)
+		if err != nil {
+			return nil, &CertificateError{Index: i, Reason: CertificateMalformed, Err: err}
+		}
+	}
+
+	cert, err := validateChain(chain, options.CertificateChain, options.Certificates)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkSigningMethod(token.Method, cert.PublicKey); err != nil {
+		return nil, err
+	}
+
+	return cert.PublicKey, nil
+}
+
+// ValidationReason identifies why a token failed validation
+type ValidationReason string
+
+// Token validation failure reasons
+const (
+	ReasonMalformed        ValidationReason = "malformed"
+	ReasonUnverifiable     ValidationReason = "unverifiable"
+	ReasonSignatureInvalid ValidationReason = "signature_invalid"
+	ReasonExpired          ValidationReason = "expired"
+	ReasonNotValidYet      ValidationReason = "not_valid_yet"
+	ReasonIssuedAt         ValidationReason = "issued_at"
+	ReasonIssuer           ValidationReason = "issuer"
+	ReasonAudience         ValidationReason = "audience"
+	ReasonClaimsInvalid    ValidationReason = "claims_invalid"
+	ReasonRevoked          ValidationReason = "revoked"
+)
+
+// validationReasons maps jwt.ValidationError flags to reasons, in order of precedence
+var validationReasons = []struct {
+	flag   uint32
+	reason ValidationReason
+}{
+	{jwt.ValidationErrorMalformed, ReasonMalformed},
+	{jwt.ValidationErrorUnverifiable, ReasonUnverifiable},
+	{jwt.ValidationErrorSignatureInvalid, ReasonSignatureInvalid},
+	{jwt.ValidationErrorExpired, ReasonExpired},
+	{jwt.ValidationErrorNotValidYet, ReasonNotValidYet},
+	{jwt.ValidationErrorIssuedAt, ReasonIssuedAt},
+	{jwt.ValidationErrorIssuer, ReasonIssuer},
+	{jwt.ValidationErrorAudience, ReasonAudience},
+	{jwt.ValidationErrorClaimsInvalid, ReasonClaimsInvalid},
+	{jwt.ValidationErrorId, ReasonRevoked},
 }
 
 // ValidationError interrogates the jwt.ValidationError, returning
 // a more detailed error message.
 type ValidationError struct {
 	VError *jwt.ValidationError
+
+	// Reason is the main reason the token failed validation, see Reasons for all of them
+	Reason ValidationReason
+
+	// Claim is the name of the claim which failed validation, if any
+	Claim string
+}
+
+func newValidationError(ve *jwt.ValidationError, claim string) *ValidationError {
+	e := &ValidationError{
+		VError: ve,
+		Claim:  claim,
+	}
+	if reasons := e.Reasons(); len(reasons) > 0 {
+		e.Reason = reasons[0]
+	}
+	return e
+}
+
+// Unwrap returns the error which caused the validation failure, such as a *CertificateError
+func (e *ValidationError) Unwrap() error {
+	return e.VError.Inner
+}
+
+// Reasons returns all the reasons the token failed validation
+func (e *ValidationError) Reasons() []ValidationReason {
+	var reasons []ValidationReason
+	for _, r := range validationReasons {
+		if e.VError.Errors&r.flag != 0 {
+			reasons = append(reasons, r.reason)
+		}
+	}
+	return reasons
+}
+
+// HasReason returns true if the token failed validation for the given reason
+func (e *ValidationError) HasReason(reason ValidationReason) bool {
+	for _, r := range e.Reasons() {
+		if r == reason {
+			return true
+		}
+	}
+	return false
 }
 
 func (e *ValidationError) Error() string {
@@ -280,5 +829,21 @@ func (e *ValidationError) Error() string {
 		return fmt.Sprintf("token NBF validation error: [%v]", e.VError)
 	}
 
+	if errs&jwt.ValidationErrorIssuedAt != 0 {
+		return fmt.Sprintf("token IAT validation error: [%v]", e.VError)
+	}
+
+	if errs&jwt.ValidationErrorIssuer != 0 {
+		return fmt.Sprintf("token issuer error: [%v]", e.VError)
+	}
+
+	if errs&jwt.ValidationErrorAudience != 0 {
+		return fmt.Sprintf("token audience error: [%v]", e.VError)
+	}
+
+	if errs&jwt.ValidationErrorId != 0 {
+		return fmt.Sprintf("token revocation error: [%v]", e.VError)
+	}
+
 	return fmt.Sprintf("token validation error: [%v]", e.VError)
 }
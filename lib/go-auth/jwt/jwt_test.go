@@ -1,9 +1,15 @@
 package jwt_test
 
 import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
 	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
+	"encoding/pem"
 	"io/ioutil"
 	"strings"
 	"testing"
@@ -146,6 +152,41 @@ func TestIsExpired(t *testing.T) {
 	require.False(t, expired)
 }
 
+func TestExpiresAt(t *testing.T) {
+	t.Parallel()
+
+	privateKey := load(t, "testdata/private-key")
+	untrustedCert := load(t, "testdata/untrusted-cert")
+
+	expiration := time.Now().Add(time.Hour * 72).Unix()
+
+	// the token is not verified, so an untrusted cert works
+	tokenStr, err := jwt.Encode(defaultIdentity(), jwt.EncodeOptions{
+		SigningKey:  privateKey,
+		Certificate: untrustedCert,
+		Expiration:  expiration,
+	})
+	require.NoError(t, err)
+
+	expiresAt, err := jwt.ExpiresAt(tokenStr)
+	require.NoError(t, err)
+	require.Equal(t, expiration, expiresAt.Unix())
+
+	_, err = jwt.ExpiresAt("foobar")
+	require.Error(t, err)
+
+	// tokens encoded without expiration have none
+	tokenStr, err = jwt.Encode(defaultIdentity(), jwt.EncodeOptions{
+		SigningKey:  privateKey,
+		Certificate: untrustedCert,
+	})
+	require.NoError(t, err)
+
+	expiresAt, err = jwt.ExpiresAt(tokenStr)
+	require.NoError(t, err)
+	require.True(t, expiresAt.IsZero())
+}
+
 func TestEncodeExpectedFields(t *testing.T) {
 	t.Parallel()
 
@@ -261,3 +302,411 @@ func TestUntrustedToken(t *testing.T) {
 	require.Error(t, err)
 	require.Regexp(t, "certificate signed by unknown authority", err)
 }
+
+func encodeTrusted(t *testing.T, options jwt.EncodeOptions) string {
+	options.SigningKey = load(t, "testdata/private-key")
+	options.Certificate = load(t, "testdata/trusted-cert")
+	if options.Expiration == 0 {
+		options.Expiration = time.Now().Add(time.Hour).Unix()
+	}
+
+	tokenStr, err := jwt.Encode(defaultIdentity(), options)
+	require.NoError(t, err)
+	return tokenStr
+}
+
+func requireValidationError(t *testing.T, err error, reason jwt.ValidationReason, claim string) {
+	require.Error(t, err)
+	ve, ok := err.(*jwt.ValidationError)
+	require.True(t, ok, "expected a *jwt.ValidationError, got %T", err)
+	require.Equal(t, reason, ve.Reason)
+	require.Equal(t, claim, ve.Claim)
+}
+
+func TestEncodeRegisteredClaims(t *testing.T) {
+	t.Parallel()
+
+	notBefore := time.Now().Add(-time.Minute).Unix()
+	tokenStr := encodeTrusted(t, jwt.EncodeOptions{
+		Issuer:    "https://login.docker.com",
+		Audience:  []string{"store"},
+		NotBefore: notBefore,
+	})
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.Split(tokenStr, ".")[1])
+	require.NoError(t, err)
+
+	var claims map[string]interface{}
+	require.NoError(t, json.Unmarshal(payload, &claims))
+	require.Equal(t, "https://login.docker.com", claims["iss"])
+	require.Equal(t, "store", claims["aud"])
+	require.Equal(t, float64(notBefore), claims["nbf"])
+
+	tokenStr = encodeTrusted(t, jwt.EncodeOptions{
+		Audience: []string{"store", "billing"},
+	})
+	payload, err = base64.RawURLEncoding.DecodeString(strings.Split(tokenStr, ".")[1])
+	require.NoError(t, err)
+
+	claims = nil
+	require.NoError(t, json.Unmarshal(payload, &claims))
+	require.Equal(t, []interface{}{"store", "billing"}, claims["aud"])
+	require.NotContains(t, claims, "iss")
+	require.NotContains(t, claims, "nbf")
+}
+
+func TestDecodeIssuer(t *testing.T) {
+	t.Parallel()
+
+	options := jwt.DecodeOptions{
+		CertificateChain: defaultRootCertChain(t),
+		Issuers:          []string{"https://login.docker.com", "https://id.docker.com"},
+	}
+
+	_, err := jwt.Decode(encodeTrusted(t, jwt.EncodeOptions{Issuer: "https://id.docker.com"}), options)
+	require.NoError(t, err)
+
+	_, err = jwt.Decode(encodeTrusted(t, jwt.EncodeOptions{Issuer: "https://evil.example.com"}), options)
+	requireValidationError(t, err, jwt.ReasonIssuer, "iss")
+	require.Regexp(t, "token issuer error", err)
+
+	_, err = jwt.Decode(encodeTrusted(t, jwt.EncodeOptions{}), options)
+	requireValidationError(t, err, jwt.ReasonIssuer, "iss")
+}
+
+func TestDecodeAudience(t *testing.T) {
+	t.Parallel()
+
+	options := jwt.DecodeOptions{
+		CertificateChain: defaultRootCertChain(t),
+		Audiences:        []string{"billing"},
+	}
+
+	_, err := jwt.Decode(encodeTrusted(t, jwt.EncodeOptions{Audience: []string{"billing"}}), options)
+	require.NoError(t, err)
+
+	_, err = jwt.Decode(encodeTrusted(t, jwt.EncodeOptions{Audience: []string{"store", "billing"}}), options)
+	require.NoError(t, err)
+
+	_, err = jwt.Decode(encodeTrusted(t, jwt.EncodeOptions{Audience: []string{"store"}}), options)
+	requireValidationError(t, err, jwt.ReasonAudience, "aud")
+	require.Regexp(t, "token audience error", err)
+
+	_, err = jwt.Decode(encodeTrusted(t, jwt.EncodeOptions{}), options)
+	requireValidationError(t, err, jwt.ReasonAudience, "aud")
+
+	// tokens are accepted for any audience unless one is expected
+	_, err = jwt.Decode(encodeTrusted(t, jwt.EncodeOptions{Audience: []string{"store"}}), jwt.DecodeOptions{
+		CertificateChain: defaultRootCertChain(t),
+	})
+	require.NoError(t, err)
+}
+
+func TestDecodeLeeway(t *testing.T) {
+	t.Parallel()
+
+	expired := encodeTrusted(t, jwt.EncodeOptions{
+		Expiration: time.Now().Add(-30 * time.Second).Unix(),
+	})
+	notYetValid := encodeTrusted(t, jwt.EncodeOptions{
+		NotBefore: time.Now().Add(30 * time.Second).Unix(),
+	})
+
+	options := jwt.DecodeOptions{
+		CertificateChain: defaultRootCertChain(t),
+	}
+
+	_, err := jwt.Decode(expired, options)
+	requireValidationError(t, err, jwt.ReasonExpired, "exp")
+	_, err = jwt.Decode(notYetValid, options)
+	requireValidationError(t, err, jwt.ReasonNotValidYet, "nbf")
+	require.Regexp(t, "token NBF validation error", err)
+
+	options.Leeway = time.Minute
+
+	_, err = jwt.Decode(expired, options)
+	require.NoError(t, err)
+	_, err = jwt.Decode(notYetValid, options)
+	require.NoError(t, err)
+
+	expiredFlag, err := jwt.IsExpired(expired, options)
+	require.NoError(t, err)
+	require.False(t, expiredFlag)
+}
+
+func TestDecodeRequiredClaims(t *testing.T) {
+	t.Parallel()
+
+	options := jwt.DecodeOptions{
+		CertificateChain: defaultRootCertChain(t),
+		RequiredClaims:   []string{"jti", "session_id"},
+	}
+
+	_, err := jwt.Decode(encodeTrusted(t, jwt.EncodeOptions{IncludeLegacyClaims: true}), options)
+	require.NoError(t, err)
+
+	_, err = jwt.Decode(encodeTrusted(t, jwt.EncodeOptions{}), options)
+	requireValidationError(t, err, jwt.ReasonClaimsInvalid, "session_id")
+	require.Regexp(t, "session_id claim not present", err)
+}
+
+func TestValidationErrorReasons(t *testing.T) {
+	t.Parallel()
+
+	tokenStr := encodeTrusted(t, jwt.EncodeOptions{
+		Expiration: time.Now().Add(-time.Hour).Unix(),
+		Issuer:     "https://evil.example.com",
+	})
+
+	_, err := jwt.Decode(tokenStr, jwt.DecodeOptions{
+		CertificateChain: defaultRootCertChain(t),
+		Issuers:          []string{"https://login.docker.com"},
+	})
+	requireValidationError(t, err, jwt.ReasonExpired, "exp")
+
+	ve := err.(*jwt.ValidationError)
+	require.Equal(t, []jwt.ValidationReason{jwt.ReasonExpired, jwt.ReasonIssuer}, ve.Reasons())
+	require.True(t, ve.HasReason(jwt.ReasonIssuer))
+	require.False(t, ve.HasReason(jwt.ReasonAudience))
+
+	// signature failures are reported with a reason too
+	_, err = jwt.Decode(tokenStr, jwt.DecodeOptions{
+		CertificateChain: x509.NewCertPool(),
+	})
+	requireValidationError(t, err, jwt.ReasonUnverifiable, "")
+}
+
+func pkcs8PEM(t *testing.T, key interface{}) []byte {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+func TestEncodeAlgorithms(t *testing.T) {
+	t.Parallel()
+
+	rsaKey := load(t, "testdata/private-key")
+
+	p256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	p256DER, err := x509.MarshalECPrivateKey(p256)
+	require.NoError(t, err)
+	p256PEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: p256DER})
+
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+
+	_, ed, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	cases := []struct {
+		alg string
+		key []byte
+	}{
+		{jwt.RS256, rsaKey},
+		{jwt.RS384, rsaKey},
+		{jwt.RS512, rsaKey},
+		{jwt.PS256, rsaKey},
+		{jwt.ES256, p256PEM},
+		{jwt.ES384, pkcs8PEM(t, p384)},
+		{jwt.EdDSA, pkcs8PEM(t, ed)},
+	}
+
+	for _, c := range cases {
+		signer, err := jwt.ParsePrivateKey(c.key)
+		require.NoError(t, err, c.alg)
+		jwk, err := jwt.NewJSONWebKey(c.alg, signer.Public())
+		require.NoError(t, err, c.alg)
+		keySet, err := jwt.NewStaticKeySet(&jwt.JSONWebKeySet{Keys: []jwt.JSONWebKey{jwk}})
+		require.NoError(t, err, c.alg)
+
+		tokenStr, err := jwt.Encode(defaultIdentity(), jwt.EncodeOptions{
+			SigningKey: c.key,
+			Algorithm:  c.alg,
+			KeyID:      c.alg,
+			Expiration: time.Now().Add(time.Hour).Unix(),
+		})
+		require.NoError(t, err, c.alg)
+
+		header, err := base64.RawURLEncoding.DecodeString(strings.Split(tokenStr, ".")[0])
+		require.NoError(t, err)
+		require.Contains(t, string(header), `"alg":"`+c.alg+`"`)
+
+		decoded, err := jwt.Decode(tokenStr, jwt.DecodeOptions{KeySet: keySet})
+		require.NoError(t, err, c.alg)
+		require.Equal(t, defaultIdentity().DockerID, decoded.DockerID)
+	}
+}
+
+func TestEncodeAlgorithmKeyMismatch(t *testing.T) {
+	t.Parallel()
+
+	p256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	for alg, key := range map[string][]byte{
+		jwt.ES256: load(t, "testdata/private-key"),
+		jwt.ES384: pkcs8PEM(t, p256),
+		jwt.RS256: pkcs8PEM(t, p256),
+		"HS256":   load(t, "testdata/private-key"),
+	} {
+		_, err := jwt.Encode(defaultIdentity(), jwt.EncodeOptions{
+			SigningKey: key,
+			Algorithm:  alg,
+			KeyID:      "key",
+			Expiration: time.Now().Add(time.Hour).Unix(),
+		})
+		require.Error(t, err, alg)
+	}
+}
+
+func TestDecodeAlgorithmAllowList(t *testing.T) {
+	t.Parallel()
+
+	tokenStr := encodeTrusted(t, jwt.EncodeOptions{Algorithm: jwt.RS512})
+	options := jwt.DecodeOptions{
+		CertificateChain: defaultRootCertChain(t),
+	}
+
+	_, err := jwt.Decode(tokenStr, options)
+	require.NoError(t, err)
+
+	options.Algorithms = []string{jwt.RS256}
+	_, err = jwt.Decode(tokenStr, options)
+	requireValidationError(t, err, jwt.ReasonSignatureInvalid, "")
+
+	// unsigned tokens are never accepted
+	parts := strings.Split(tokenStr, ".")
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`))
+	_, err = jwt.Decode(header+"."+parts[1]+".", jwt.DecodeOptions{
+		CertificateChain: defaultRootCertChain(t),
+	})
+	requireValidationError(t, err, jwt.ReasonSignatureInvalid, "")
+}
+
+func TestParsePublicKey(t *testing.T) {
+	t.Parallel()
+
+	key, err := jwt.ParsePublicKey(load(t, "testdata/trusted-cert"))
+	require.NoError(t, err)
+	_, ok := key.(*rsa.PublicKey)
+	require.True(t, ok)
+
+	_, ed, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(ed.Public())
+	require.NoError(t, err)
+
+	key, err = jwt.ParsePublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	require.NoError(t, err)
+	require.Equal(t, ed.Public(), key)
+
+	_, err = jwt.ParsePublicKey([]byte("foo"))
+	require.Error(t, err)
+}
+
+func TestEncodeCustomClaims(t *testing.T) {
+	t.Parallel()
+
+	identity := defaultIdentity()
+	identity.FullName = "Test User"
+	identity.Claims = map[string]interface{}{
+		"org_ids": []string{"org-1", "org-2"},
+		"tier":    "enterprise",
+		"seats":   25,
+	}
+
+	privateKey := load(t, "testdata/private-key")
+	tokenStr, err := jwt.Encode(identity, jwt.EncodeOptions{
+		SigningKey:  privateKey,
+		Certificate: load(t, "testdata/trusted-cert"),
+		Expiration:  time.Now().Add(time.Hour).Unix(),
+		Issuer:      "hub",
+	})
+	require.NoError(t, err)
+
+	decoded, err := jwt.Decode(tokenStr, jwt.DecodeOptions{
+		CertificateChain: defaultRootCertChain(t),
+	})
+	require.NoError(t, err)
+	require.Equal(t, identity.FullName, decoded.FullName)
+
+	// registered claims are not custom claims
+	require.Len(t, decoded.Claims, 3)
+	orgs, ok := decoded.StringsClaim("org_ids")
+	require.True(t, ok)
+	require.Equal(t, []string{"org-1", "org-2"}, orgs)
+	tier, ok := decoded.StringClaim("tier")
+	require.True(t, ok)
+	require.Equal(t, "enterprise", tier)
+	seats, ok := decoded.IntClaim("seats")
+	require.True(t, ok)
+	require.Equal(t, int64(25), seats)
+
+	// tokens without custom claims decode to nil claims
+	decoded, err = jwt.Decode(encodeTrusted(t, jwt.EncodeOptions{}), jwt.DecodeOptions{
+		CertificateChain: defaultRootCertChain(t),
+	})
+	require.NoError(t, err)
+	require.Empty(t, decoded.FullName)
+	require.Nil(t, decoded.Claims)
+
+	// custom claims cannot override the identity or registered claims
+	for _, name := range []string{"sub", "exp", "scope", "name"} {
+		identity.Claims = map[string]interface{}{name: "x"}
+		_, err = jwt.Encode(identity, jwt.EncodeOptions{
+			SigningKey:  privateKey,
+			Certificate: load(t, "testdata/trusted-cert"),
+		})
+		require.Error(t, err)
+		require.Regexp(t, "reserved", err)
+	}
+}
+
+func TestEncodeActor(t *testing.T) {
+	t.Parallel()
+
+	id := defaultIdentity()
+	id.Actor = &identity.Actor{
+		Subject: "billing-gateway",
+		Actor:   &identity.Actor{Subject: "store-frontend"},
+	}
+	tokenStr, err := jwt.Encode(id, jwt.EncodeOptions{
+		SigningKey:  load(t, "testdata/private-key"),
+		Certificate: load(t, "testdata/trusted-cert"),
+		Expiration:  time.Now().Add(time.Hour).Unix(),
+	})
+	require.NoError(t, err)
+
+	in := jwt.Inspect(tokenStr, jwt.DecodeOptions{})
+	require.Equal(t, map[string]interface{}{
+		"sub": "billing-gateway",
+		"act": map[string]interface{}{"sub": "store-frontend"},
+	}, in.Claims["act"])
+
+	decoded, err := jwt.Decode(tokenStr, jwt.DecodeOptions{
+		CertificateChain: defaultRootCertChain(t),
+	})
+	require.NoError(t, err)
+	require.Equal(t, id.DockerID, decoded.DockerID)
+	require.Equal(t, id.Actor, decoded.Actor)
+	require.Equal(t, []string{"billing-gateway", "store-frontend"}, decoded.DelegationChain())
+	require.Nil(t, decoded.Claims)
+
+	// actors must have a subject
+	id.Actor = &identity.Actor{Subject: "billing-gateway", Actor: &identity.Actor{}}
+	_, err = jwt.Encode(id, jwt.EncodeOptions{
+		SigningKey:  load(t, "testdata/private-key"),
+		Certificate: load(t, "testdata/trusted-cert"),
+	})
+	require.Regexp(t, "actor subject is required", err)
+
+	// the act claim cannot be set as a custom claim
+	id.Actor = nil
+	id.Claims = map[string]interface{}{"act": map[string]interface{}{"sub": "billing-gateway"}}
+	_, err = jwt.Encode(id, jwt.EncodeOptions{
+		SigningKey:  load(t, "testdata/private-key"),
+		Certificate: load(t, "testdata/trusted-cert"),
+	})
+	require.Regexp(t, "reserved", err)
+}
@@ -0,0 +1,209 @@
+package jwt
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// RevocationChecker reports whether tokens have been revoked, by their jti claim or, for tokens encoded with
+// IncludeLegacyClaims, their session_id claim. Implementations backed by external stores (e.g. redis, or a
+// database) should return an error rather than false if the store cannot be queried: Decode then rejects the
+// token.
+type RevocationChecker interface {
+	IsRevoked(id string) (bool, error)
+}
+
+// checkRevocation returns a ValidationError if the jti or legacy session_id of the token has been revoked
+func checkRevocation(claims jwt.MapClaims, checker RevocationChecker) error {
+	checked := map[string]bool{}
+	for _, name := range []string{jti, sessionid} {
+		id, _ := claims[name].(string)
+		if id == "" || checked[id] {
+			continue
+		}
+		checked[id] = true
+
+		revoked, err := checker.IsRevoked(id)
+		if err != nil {
+			return fmt.Errorf("failed to check token revocation: %s", err)
+		}
+		if revoked {
+			return newValidationError(jwt.NewValidationError("token has been revoked", jwt.ValidationErrorId), name)
+		}
+	}
+	return nil
+}
+
+// MemoryRevocationList is an in-memory RevocationChecker. Revoked ids are kept until the revoked token would
+// have expired anyway.
+type MemoryRevocationList struct {
+	mu      sync.Mutex
+	revoked map[string]time.Time
+}
+
+// NewMemoryRevocationList returns an empty MemoryRevocationList
+func NewMemoryRevocationList() *MemoryRevocationList {
+	return &MemoryRevocationList{
+		revoked: make(map[string]time.Time),
+	}
+}
+
+// Revoke revokes the token id until the given time, usually the token expiration. A zero time revokes the id
+// for good.
+func (l *MemoryRevocationList) Revoke(id string, until time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.purge(time.Now())
+	l.revoked[id] = until
+}
+
+// RevokeToken revokes the jti and legacy session_id of the token until it expires, or for good if it has no
+// expiration. The token is NOT verified.
+func (l *MemoryRevocationList) RevokeToken(tokenStr string) error {
+	claims, err := unverifiedClaims(tokenStr)
+	if err != nil {
+		return err
+	}
+
+	until, _, err := timeClaim(claims, exp)
+	if err != nil {
+		return err
+	}
+	if until.Unix() <= 0 {
+		// tokens without expiration, or with exp 0 as Encode writes by default, are revoked for good
+		until = time.Time{}
+	}
+
+	var revoked bool
+	for _, name := range []string{jti, sessionid} {
+		if id, _ := claims[name].(string); id != "" {
+			l.Revoke(id, until)
+			revoked = true
+		}
+	}
+	if !revoked {
+		return fmt.Errorf("%v claim not present", jti)
+	}
+	return nil
+}
+
+// IsRevoked implements RevocationChecker
+func (l *MemoryRevocationList) IsRevoked(id string) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	until, ok := l.revoked[id]
+	if !ok {
+		return false, nil
+	}
+	if !until.IsZero() && time.Now().After(until) {
+		delete(l.revoked, id)
+		return false, nil
+	}
+	return true, nil
+}
+
+// purge drops the ids of tokens which have since expired
+func (l *MemoryRevocationList) purge(now time.Time) {
+	for id, until := range l.revoked {
+		if !until.IsZero() && now.After(until) {
+			delete(l.revoked, id)
+		}
+	}
+}
+
+// FileRevocationList is a RevocationChecker reading revoked ids from a file, one per line. Blank lines and lines
+// starting with # are ignored. The file is read again whenever it changes, so that ids can be revoked by
+// updating it, e.g. from a config map.
+type FileRevocationList struct {
+	path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	revoked map[string]bool
+}
+
+// NewFileRevocationList returns a FileRevocationList reading the file at path, returning an error if it cannot
+// be read
+func NewFileRevocationList(path string) (*FileRevocationList, error) {
+	l := &FileRevocationList{path: path}
+	if err := l.Reload(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// IsRevoked implements RevocationChecker
+func (l *FileRevocationList) IsRevoked(id string) (bool, error) {
+	if err := l.reloadIfChanged(); err != nil {
+		return false, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.revoked[id], nil
+}
+
+// Reload reads the file again
+func (l *FileRevocationList) Reload() error {
+	info, err := os.Stat(l.path)
+	if err != nil {
+		return err
+	}
+	return l.load(info)
+}
+
+func (l *FileRevocationList) reloadIfChanged() error {
+	info, err := os.Stat(l.path)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	changed := !info.ModTime().Equal(l.modTime) || info.Size() != l.size
+	l.mu.Unlock()
+
+	if !changed {
+		return nil
+	}
+	return l.load(info)
+}
+
+func (l *FileRevocationList) load(info os.FileInfo) error {
+	data, err := ioutil.ReadFile(l.path)
+	if err != nil {
+		return err
+	}
+
+	revoked := make(map[string]bool)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		revoked[line] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("invalid revocation list %s: %s", l.path, err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.revoked = revoked
+	l.modTime = info.ModTime()
+	l.size = info.Size()
+	return nil
+}
@@ -0,0 +1,167 @@
+package jwt_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/docker/licensing/lib/go-auth/jwt"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryRevocationList(t *testing.T) {
+	t.Parallel()
+
+	list := jwt.NewMemoryRevocationList()
+	options := jwt.DecodeOptions{
+		CertificateChain:  defaultRootCertChain(t),
+		RevocationChecker: list,
+	}
+
+	tokenStr := encodeTrusted(t, jwt.EncodeOptions{Jti: "jti-1"})
+	_, err := jwt.Decode(tokenStr, options)
+	require.NoError(t, err)
+
+	list.Revoke("jti-1", time.Now().Add(time.Hour))
+	_, err = jwt.Decode(tokenStr, options)
+	requireValidationError(t, err, jwt.ReasonRevoked, "jti")
+	require.Regexp(t, "token revocation error", err)
+
+	// ids are forgotten once the tokens they revoke have expired
+	list.Revoke("jti-2", time.Now().Add(-time.Second))
+	revoked, err := list.IsRevoked("jti-2")
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	// zero times revoke for good
+	list.Revoke("jti-3", time.Time{})
+	revoked, err = list.IsRevoked("jti-3")
+	require.NoError(t, err)
+	require.True(t, revoked)
+}
+
+func TestMemoryRevocationListRevokeToken(t *testing.T) {
+	t.Parallel()
+
+	list := jwt.NewMemoryRevocationList()
+	options := jwt.DecodeOptions{
+		CertificateChain:  defaultRootCertChain(t),
+		RevocationChecker: list,
+	}
+
+	tokenStr := encodeTrusted(t, jwt.EncodeOptions{IncludeLegacyClaims: true})
+	require.NoError(t, list.RevokeToken(tokenStr))
+
+	_, err := jwt.Decode(tokenStr, options)
+	requireValidationError(t, err, jwt.ReasonRevoked, "jti")
+
+	require.Error(t, list.RevokeToken("foo"))
+}
+
+func TestMemoryRevocationListRevokeTokenWithoutExpiration(t *testing.T) {
+	t.Parallel()
+
+	list := jwt.NewMemoryRevocationList()
+
+	// Encode writes exp 0 when no expiration is set
+	tokenStr, err := jwt.Encode(defaultIdentity(), jwt.EncodeOptions{
+		SigningKey:  load(t, "testdata/private-key"),
+		Certificate: load(t, "testdata/trusted-cert"),
+		Jti:         "jti-1",
+	})
+	require.NoError(t, err)
+	require.NoError(t, list.RevokeToken(tokenStr))
+
+	revoked, err := list.IsRevoked("jti-1")
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	// the revocation is not purged when other ids are revoked
+	list.Revoke("jti-2", time.Time{})
+	revoked, err = list.IsRevoked("jti-1")
+	require.NoError(t, err)
+	require.True(t, revoked)
+}
+
+func TestRevokedLegacySessionID(t *testing.T) {
+	t.Parallel()
+
+	list := jwt.NewMemoryRevocationList()
+	options := jwt.DecodeOptions{
+		CertificateChain:  defaultRootCertChain(t),
+		RevocationChecker: list,
+	}
+
+	// gateways revoke sessions by session_id, which legacy tokens hold along with the jti
+	list.Revoke("session-1", time.Time{})
+
+	tokenStr := encodeTrusted(t, jwt.EncodeOptions{Jti: "session-1", IncludeLegacyClaims: true})
+	_, err := jwt.Decode(tokenStr, options)
+	requireValidationError(t, err, jwt.ReasonRevoked, "jti")
+
+	// other tokens are unaffected
+	_, err = jwt.Decode(encodeTrusted(t, jwt.EncodeOptions{IncludeLegacyClaims: true}), options)
+	require.NoError(t, err)
+}
+
+type failingRevocationChecker struct{}
+
+func (failingRevocationChecker) IsRevoked(id string) (bool, error) {
+	return false, fmt.Errorf("store unavailable")
+}
+
+func TestRevocationCheckerError(t *testing.T) {
+	t.Parallel()
+
+	_, err := jwt.Decode(encodeTrusted(t, jwt.EncodeOptions{}), jwt.DecodeOptions{
+		CertificateChain:  defaultRootCertChain(t),
+		RevocationChecker: failingRevocationChecker{},
+	})
+	require.Error(t, err)
+	require.Regexp(t, "failed to check token revocation: store unavailable", err)
+}
+
+func TestFileRevocationList(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "revocation")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "revoked")
+	require.NoError(t, ioutil.WriteFile(path, []byte("# revoked tokens\njti-1\n\n  jti-2  \n"), 0644))
+
+	_, err = jwt.NewFileRevocationList(filepath.Join(dir, "missing"))
+	require.Error(t, err)
+
+	list, err := jwt.NewFileRevocationList(path)
+	require.NoError(t, err)
+
+	for id, expected := range map[string]bool{"jti-1": true, "jti-2": true, "jti-3": false, "# revoked tokens": false} {
+		revoked, err := list.IsRevoked(id)
+		require.NoError(t, err)
+		require.Equal(t, expected, revoked, id)
+	}
+
+	// changes to the file are picked up
+	require.NoError(t, ioutil.WriteFile(path, []byte("jti-1\njti-2\njti-3\n"), 0644))
+	revoked, err := list.IsRevoked("jti-3")
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	options := jwt.DecodeOptions{
+		CertificateChain:  defaultRootCertChain(t),
+		RevocationChecker: list,
+	}
+	_, err = jwt.Decode(encodeTrusted(t, jwt.EncodeOptions{Jti: "jti-3"}), options)
+	requireValidationError(t, err, jwt.ReasonRevoked, "jti")
+
+	// tokens are rejected if the list cannot be read
+	require.NoError(t, os.Remove(path))
+	_, err = jwt.Decode(encodeTrusted(t, jwt.EncodeOptions{Jti: "jti-4"}), options)
+	require.Error(t, err)
+}
@@ -9,6 +9,7 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"strings"
 
 	"github.com/docker/licensing/lib/errors"
@@ -188,6 +189,20 @@ func SendJSON(sends interface{}) RequestOption {
 	}
 }
 
+// SendForm returns a RequestOption that will encode
+// and set the url encoded form body & headers on a request.
+func SendForm(sends url.Values) RequestOption {
+	return func(r *Request) {
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.RequestPrepare = func(r *Request) error {
+			body := strings.NewReader(sends.Encode())
+			r.SetBody(body)
+			r.ContentLength = int64(body.Len())
+			return nil
+		}
+	}
+}
+
 // RecvJSON returns a RequestOption that will set the json headers
 // on a request, and set a ResponseHandler that will unmarshal
 // the response body to the given interface{}.
@@ -7,6 +7,8 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"reflect"
 	"testing"
 
 	"github.com/docker/licensing/lib/errors"
@@ -50,7 +52,7 @@ func TestJSONSuccessPath(t *testing.T) {
 	req, err := New(ctx, "GET", server.URL, SendJSON(sends), RecvJSON(&recvs))
 	require.NoError(t, err)
 	require.Equal(t, req.Method, "GET")
-	require.NotEqual(t, req.ResponseHandle, DefaultResponseHandle)
+	require.NotEqual(t, reflect.ValueOf(DefaultResponseHandle).Pointer(), reflect.ValueOf(req.ResponseHandle).Pointer())
 	h := req.Header.Get("Accept")
 	require.Equal(t, "application/json", h)
 	h = req.Header.Get("Accept-Charset")
@@ -70,6 +72,37 @@ func TestJSONSuccessPath(t *testing.T) {
 	require.Regexp(t, "read on closed response body", err.Error())
 }
 
+func TestFormSuccessPath(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NotZero(t, r.ContentLength)
+		require.NoError(t, r.ParseForm())
+		require.Equal(t, "send1", r.PostForm.Get("sendfield"))
+		require.Equal(t, []string{"a", "b"}, r.PostForm["multi"])
+
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("recv1"))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+
+	sends := url.Values{
+		"sendfield": {"send1"},
+		"multi":     {"a", "b"},
+	}
+	var recvs string
+	req, err := New(ctx, "POST", server.URL, SendForm(sends), RecvText(&recvs))
+	require.NoError(t, err)
+	h := req.Header.Get("Content-Type")
+	require.Equal(t, "application/x-www-form-urlencoded", h)
+
+	_, err = req.Do()
+	require.NoError(t, err)
+	require.Equal(t, "recv1", recvs)
+}
+
 func TestXMLSuccessPath(t *testing.T) {
 	t.Parallel()
 
@@ -107,7 +140,7 @@ func TestXMLSuccessPath(t *testing.T) {
 	req, err := New(ctx, "GET", server.URL, SendXML(sends), RecvXML(&recvs))
 	require.NoError(t, err)
 	require.Equal(t, req.Method, "GET")
-	require.NotEqual(t, req.ResponseHandle, DefaultResponseHandle)
+	require.NotEqual(t, reflect.ValueOf(DefaultResponseHandle).Pointer(), reflect.ValueOf(req.ResponseHandle).Pointer())
 	h := req.Header.Get("Accept")
 	require.Equal(t, "application/xml", h)
 	h = req.Header.Get("Accept-Charset")
@@ -150,7 +183,7 @@ func TestTextSuccessPath(t *testing.T) {
 	req, err := New(ctx, "GET", server.URL, SendText(sends), RecvText(&recvs))
 	require.NoError(t, err)
 	require.Equal(t, req.Method, "GET")
-	require.NotEqual(t, req.ResponseHandle, DefaultResponseHandle)
+	require.NotEqual(t, reflect.ValueOf(DefaultResponseHandle).Pointer(), reflect.ValueOf(req.ResponseHandle).Pointer())
 	h := req.Header.Get("Accept")
 	require.Equal(t, "text/plain", h)
 	h = req.Header.Get("Accept-Charset")
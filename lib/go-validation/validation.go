@@ -3,6 +3,7 @@ package validation
 import (
 	"fmt"
 	"reflect"
+	"strings"
 
 	validator "github.com/asaskevich/govalidator"
 )
@@ -54,6 +55,16 @@ func (e *Error) MsgForCode() string {
 // Errors is a list of validation Errors
 type Errors []*Error
 
+// Error returns the string representation of all the errors, allowing
+// Errors to be returned as an error
+func (errs Errors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, e := range errs {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
 // Validater has a single function Validate, which can be used to determine
 // if the interface implementation is valid. Validate should return true if the implementation
 // passes validation, false otherwise. If invalid, a list of one or more validation
@@ -122,6 +133,15 @@ func InvalidEmpty(fieldName string) *Error {
 	}
 }
 
+// InvalidValue returns a general validation error for the given field name and value
+func InvalidValue(fieldName string, fieldValue interface{}) *Error {
+	return &Error{
+		FieldName:  fieldName,
+		FieldValue: fieldValue,
+		kind:       ErrorGeneral,
+	}
+}
+
 // InvalidEmail returns an invalid validation error for the given field name and value
 func InvalidEmail(fieldName, fieldValue string) *Error {
 	return &Error{
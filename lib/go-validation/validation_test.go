@@ -169,4 +169,9 @@ func TestValidateIsInvalid(t *testing.T) {
 	require.Equal(t, "last_name invalid: not provided", errs[0].Error())
 	require.Equal(t, "address[0] city invalid: not provided", errs[1].Error())
 	require.Equal(t, "address[0] zip invalid: '25' is not a valid value", errs[2].Error())
+
+	// all errors, as an error
+	var err error = errs
+	require.Equal(t, "last_name invalid: not provided; address[0] city invalid: not provided; "+
+		"address[0] zip invalid: '25' is not a valid value", err.Error())
 }
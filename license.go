@@ -20,7 +20,7 @@ func (c *client) getLicenseFile(ctx context.Context, subID string) (*model.Issue
 	url.Path += fmt.Sprintf("/api/billing/v4/subscriptions/%s/license-file", subID)
 
 	license := new(model.IssuedLicense)
-	if _, _, err := c.doReq(ctx, "GET", &url, clientlib.RecvJSON(license)); err != nil {
+	if _, _, err := c.doReq(ctx, "GET", &url, clientlib.RecvJSON(license), notFoundErrorOpt(ErrSubscriptionNotFound)); err != nil {
 		return nil, err
 	}
 
@@ -41,21 +41,33 @@ func (c *client) check(ctx context.Context, license model.IssuedLicense) (*model
 	// TODO: Mason - replace this parseJWS with a non libtrust lib
 	signature, err := libtrust.ParseJWS(authorization)
 	if err != nil {
-		return nil, errors.Wrapf(err, errors.Fields{
+		return nil, errors.Wrapf(&VerificationError{
+			KeyID:  keyID,
+			Reason: ErrInvalidSignature,
+			Err:    err,
+		}, errors.Fields{
 			"key_id": keyID,
 		}, "license parse JWS failed")
 	}
 
 	keys, err := signature.Verify()
 	if err != nil {
-		return nil, errors.Wrapf(err, errors.Fields{
+		return nil, errors.Wrapf(&VerificationError{
+			KeyID:  keyID,
+			Reason: ErrInvalidSignature,
+			Err:    err,
+		}, errors.Fields{
 			"key_id": keyID,
 		}, "license signature verification failed")
 	}
 
 	keyCnt := len(keys)
 	if keyCnt != 1 {
-		err = fmt.Errorf("unexpected number of signing keys (%d)", keyCnt)
+		err = &VerificationError{
+			KeyID:  keyID,
+			Reason: ErrInvalidSignature,
+			Err:    fmt.Errorf("unexpected number of signing keys (%d)", keyCnt),
+		}
 		return nil, errors.WithStack(err).With(errors.Fields{
 			"key_id": keyID,
 		})
@@ -64,7 +76,12 @@ func (c *client) check(ctx context.Context, license model.IssuedLicense) (*model
 	key := keys[0]
 
 	if !c.recognizedSigningKey(key) {
-		return nil, errors.New("unrecognized signing key")
+		return nil, errors.WithStack(&VerificationError{
+			KeyID:  keyID,
+			Reason: ErrUnrecognizedSigningKey,
+		}).With(errors.Fields{
+			"key_id": keyID,
+		})
 	}
 
 	payload, err := signature.Payload()
@@ -85,7 +102,11 @@ func (c *client) check(ctx context.Context, license model.IssuedLicense) (*model
 
 	msg := checkRes.Expiration.Format(time.RFC3339)
 	if err := checkToken(msg, checkRes.Token, privateKey); err != nil {
-		return nil, errors.Wrap(err, errors.Fields{
+		return nil, errors.Wrap(&VerificationError{
+			KeyID:  keyID,
+			Reason: ErrInvalidLicenseToken,
+			Err:    err,
+		}, errors.Fields{
 			"key_id": keyID,
 		})
 	}
@@ -124,21 +145,21 @@ func (c *client) getAuthorization(ctx context.Context, license model.IssuedLicen
 func checkToken(message, token, privateKey string) error {
 	tokenBytes, err := base64.URLEncoding.DecodeString(token)
 	if err != nil {
-		return errors.Wrap(err, errors.Fields{"token": token})
+		return errors.Wrap(err, errors.Fields{"token": errors.Redact(token)})
 	}
 
 	generatedToken, err := generateToken(message, privateKey)
 	if err != nil {
-		return errors.Wrap(err, errors.Fields{"token": token})
+		return errors.Wrap(err, errors.Fields{"token": errors.Redact(token)})
 	}
 
 	generatedBytes, err := base64.URLEncoding.DecodeString(generatedToken)
 	if err != nil {
-		return errors.Wrap(err, errors.Fields{"token": token})
+		return errors.Wrap(err, errors.Fields{"token": errors.Redact(token)})
 	}
 
 	if !hmac.Equal(tokenBytes, generatedBytes) {
-		return errors.Forbidden(errors.Fields{"token": token}, "invalid token")
+		return errors.Forbidden(errors.Fields{"token": errors.Redact(token)}, "invalid token")
 	}
 
 	return nil
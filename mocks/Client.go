@@ -1,20 +1,154 @@
+// Code generated by mockery. DO NOT EDIT.
+
 package mocks
 
-import context "context"
-import licensing "github.com/docker/licensing"
-import mock "github.com/stretchr/testify/mock"
-import model "github.com/docker/licensing/model"
+import (
+	context "context"
+
+	licensing "github.com/docker/licensing"
+	mock "github.com/stretchr/testify/mock"
+
+	model "github.com/docker/licensing/model"
+)
 
 // Client is an autogenerated mock type for the Client type
 type Client struct {
 	mock.Mock
 }
 
+// AllHubUserOrgs provides a mock function with given fields: ctx, authToken, pageSize
+func (_m *Client) AllHubUserOrgs(ctx context.Context, authToken string, pageSize int) ([]model.Org, error) {
+	ret := _m.Called(ctx, authToken, pageSize)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AllHubUserOrgs")
+	}
+
+	var r0 []model.Org
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) ([]model.Org, error)); ok {
+		return rf(ctx, authToken, pageSize)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) []model.Org); ok {
+		r0 = rf(ctx, authToken, pageSize)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.Org)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, int) error); ok {
+		r1 = rf(ctx, authToken, pageSize)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CancelSubscription provides a mock function with given fields: ctx, authToken, subscriptionID, request
+func (_m *Client) CancelSubscription(ctx context.Context, authToken string, subscriptionID string, request *model.SubscriptionCancellationRequest) (*model.SubscriptionDetail, error) {
+	ret := _m.Called(ctx, authToken, subscriptionID, request)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CancelSubscription")
+	}
+
+	var r0 *model.SubscriptionDetail
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, *model.SubscriptionCancellationRequest) (*model.SubscriptionDetail, error)); ok {
+		return rf(ctx, authToken, subscriptionID, request)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, *model.SubscriptionCancellationRequest) *model.SubscriptionDetail); ok {
+		r0 = rf(ctx, authToken, subscriptionID, request)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.SubscriptionDetail)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, *model.SubscriptionCancellationRequest) error); ok {
+		r1 = rf(ctx, authToken, subscriptionID, request)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CheckTrialEligibility provides a mock function with given fields: ctx, authToken, dockerID
+func (_m *Client) CheckTrialEligibility(ctx context.Context, authToken string, dockerID string) (*model.TrialEligibility, error) {
+	ret := _m.Called(ctx, authToken, dockerID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CheckTrialEligibility")
+	}
+
+	var r0 *model.TrialEligibility
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*model.TrialEligibility, error)); ok {
+		return rf(ctx, authToken, dockerID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *model.TrialEligibility); ok {
+		r0 = rf(ctx, authToken, dockerID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.TrialEligibility)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, authToken, dockerID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateSubscription provides a mock function with given fields: ctx, authToken, request
+func (_m *Client) CreateSubscription(ctx context.Context, authToken string, request *model.SubscriptionCreationRequest) (*model.SubscriptionDetail, error) {
+	ret := _m.Called(ctx, authToken, request)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateSubscription")
+	}
+
+	var r0 *model.SubscriptionDetail
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, *model.SubscriptionCreationRequest) (*model.SubscriptionDetail, error)); ok {
+		return rf(ctx, authToken, request)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, *model.SubscriptionCreationRequest) *model.SubscriptionDetail); ok {
+		r0 = rf(ctx, authToken, request)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.SubscriptionDetail)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, *model.SubscriptionCreationRequest) error); ok {
+		r1 = rf(ctx, authToken, request)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // DownloadLicenseFromHub provides a mock function with given fields: ctx, authToken, subscriptionID
 func (_m *Client) DownloadLicenseFromHub(ctx context.Context, authToken string, subscriptionID string) (*model.IssuedLicense, error) {
 	ret := _m.Called(ctx, authToken, subscriptionID)
 
+	if len(ret) == 0 {
+		panic("no return value specified for DownloadLicenseFromHub")
+	}
+
 	var r0 *model.IssuedLicense
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*model.IssuedLicense, error)); ok {
+		return rf(ctx, authToken, subscriptionID)
+	}
 	if rf, ok := ret.Get(0).(func(context.Context, string, string) *model.IssuedLicense); ok {
 		r0 = rf(ctx, authToken, subscriptionID)
 	} else {
@@ -23,7 +157,6 @@ func (_m *Client) DownloadLicenseFromHub(ctx context.Context, authToken string,
 		}
 	}
 
-	var r1 error
 	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
 		r1 = rf(ctx, authToken, subscriptionID)
 	} else {
@@ -33,20 +166,27 @@ func (_m *Client) DownloadLicenseFromHub(ctx context.Context, authToken string,
 	return r0, r1
 }
 
-// GenerateNewTrialSubscription provides a mock function with given fields: ctx, authToken, dockerID, email
-func (_m *Client) GenerateNewTrialSubscription(ctx context.Context, authToken string, dockerID string, email string) (string, error) {
-	ret := _m.Called(ctx, authToken, dockerID, email)
+// GenerateNewTrialSubscription provides a mock function with given fields: ctx, authToken, dockerID
+func (_m *Client) GenerateNewTrialSubscription(ctx context.Context, authToken string, dockerID string) (string, error) {
+	ret := _m.Called(ctx, authToken, dockerID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GenerateNewTrialSubscription")
+	}
 
 	var r0 string
-	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) string); ok {
-		r0 = rf(ctx, authToken, dockerID, email)
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (string, error)); ok {
+		return rf(ctx, authToken, dockerID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) string); ok {
+		r0 = rf(ctx, authToken, dockerID)
 	} else {
 		r0 = ret.Get(0).(string)
 	}
 
-	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, string, string, string) error); ok {
-		r1 = rf(ctx, authToken, dockerID, email)
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, authToken, dockerID)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -58,7 +198,15 @@ func (_m *Client) GenerateNewTrialSubscription(ctx context.Context, authToken st
 func (_m *Client) GetHubUserByName(ctx context.Context, username string) (*model.User, error) {
 	ret := _m.Called(ctx, username)
 
+	if len(ret) == 0 {
+		panic("no return value specified for GetHubUserByName")
+	}
+
 	var r0 *model.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*model.User, error)); ok {
+		return rf(ctx, username)
+	}
 	if rf, ok := ret.Get(0).(func(context.Context, string) *model.User); ok {
 		r0 = rf(ctx, username)
 	} else {
@@ -67,7 +215,6 @@ func (_m *Client) GetHubUserByName(ctx context.Context, username string) (*model
 		}
 	}
 
-	var r1 error
 	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
 		r1 = rf(ctx, username)
 	} else {
@@ -81,7 +228,15 @@ func (_m *Client) GetHubUserByName(ctx context.Context, username string) (*model
 func (_m *Client) GetHubUserOrgs(ctx context.Context, authToken string) ([]model.Org, error) {
 	ret := _m.Called(ctx, authToken)
 
+	if len(ret) == 0 {
+		panic("no return value specified for GetHubUserOrgs")
+	}
+
 	var r0 []model.Org
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]model.Org, error)); ok {
+		return rf(ctx, authToken)
+	}
 	if rf, ok := ret.Get(0).(func(context.Context, string) []model.Org); ok {
 		r0 = rf(ctx, authToken)
 	} else {
@@ -90,7 +245,6 @@ func (_m *Client) GetHubUserOrgs(ctx context.Context, authToken string) ([]model
 		}
 	}
 
-	var r1 error
 	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
 		r1 = rf(ctx, authToken)
 	} else {
@@ -100,11 +254,119 @@ func (_m *Client) GetHubUserOrgs(ctx context.Context, authToken string) ([]model
 	return r0, r1
 }
 
+// GetSubscription provides a mock function with given fields: ctx, authToken, subscriptionID
+func (_m *Client) GetSubscription(ctx context.Context, authToken string, subscriptionID string) (*model.SubscriptionDetail, error) {
+	ret := _m.Called(ctx, authToken, subscriptionID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetSubscription")
+	}
+
+	var r0 *model.SubscriptionDetail
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*model.SubscriptionDetail, error)); ok {
+		return rf(ctx, authToken, subscriptionID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *model.SubscriptionDetail); ok {
+		r0 = rf(ctx, authToken, subscriptionID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.SubscriptionDetail)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, authToken, subscriptionID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// IterateHubUserOrgs provides a mock function with given fields: ctx, authToken, pageSize
+func (_m *Client) IterateHubUserOrgs(ctx context.Context, authToken string, pageSize int) *licensing.Paginator[model.Org] {
+	ret := _m.Called(ctx, authToken, pageSize)
+
+	if len(ret) == 0 {
+		panic("no return value specified for IterateHubUserOrgs")
+	}
+
+	var r0 *licensing.Paginator[model.Org]
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) *licensing.Paginator[model.Org]); ok {
+		r0 = rf(ctx, authToken, pageSize)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*licensing.Paginator[model.Org])
+		}
+	}
+
+	return r0
+}
+
+// IterateSubscriptions provides a mock function with given fields: ctx, authToken, query
+func (_m *Client) IterateSubscriptions(ctx context.Context, authToken string, query *model.SubscriptionQuery) *licensing.SubscriptionIterator {
+	ret := _m.Called(ctx, authToken, query)
+
+	if len(ret) == 0 {
+		panic("no return value specified for IterateSubscriptions")
+	}
+
+	var r0 *licensing.SubscriptionIterator
+	if rf, ok := ret.Get(0).(func(context.Context, string, *model.SubscriptionQuery) *licensing.SubscriptionIterator); ok {
+		r0 = rf(ctx, authToken, query)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*licensing.SubscriptionIterator)
+		}
+	}
+
+	return r0
+}
+
+// ListAccessibleSubscriptions provides a mock function with given fields: ctx, authToken, dockerID
+func (_m *Client) ListAccessibleSubscriptions(ctx context.Context, authToken string, dockerID string) (*model.AccessibleSubscriptions, error) {
+	ret := _m.Called(ctx, authToken, dockerID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListAccessibleSubscriptions")
+	}
+
+	var r0 *model.AccessibleSubscriptions
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*model.AccessibleSubscriptions, error)); ok {
+		return rf(ctx, authToken, dockerID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *model.AccessibleSubscriptions); ok {
+		r0 = rf(ctx, authToken, dockerID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.AccessibleSubscriptions)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, authToken, dockerID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // ListSubscriptions provides a mock function with given fields: ctx, authToken, dockerID
 func (_m *Client) ListSubscriptions(ctx context.Context, authToken string, dockerID string) ([]*model.Subscription, error) {
 	ret := _m.Called(ctx, authToken, dockerID)
 
+	if len(ret) == 0 {
+		panic("no return value specified for ListSubscriptions")
+	}
+
 	var r0 []*model.Subscription
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) ([]*model.Subscription, error)); ok {
+		return rf(ctx, authToken, dockerID)
+	}
 	if rf, ok := ret.Get(0).(func(context.Context, string, string) []*model.Subscription); ok {
 		r0 = rf(ctx, authToken, dockerID)
 	} else {
@@ -113,7 +375,6 @@ func (_m *Client) ListSubscriptions(ctx context.Context, authToken string, docke
 		}
 	}
 
-	var r1 error
 	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
 		r1 = rf(ctx, authToken, dockerID)
 	} else {
@@ -127,7 +388,15 @@ func (_m *Client) ListSubscriptions(ctx context.Context, authToken string, docke
 func (_m *Client) ListSubscriptionsDetails(ctx context.Context, authToken string, dockerID string) ([]*model.SubscriptionDetail, error) {
 	ret := _m.Called(ctx, authToken, dockerID)
 
+	if len(ret) == 0 {
+		panic("no return value specified for ListSubscriptionsDetails")
+	}
+
 	var r0 []*model.SubscriptionDetail
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) ([]*model.SubscriptionDetail, error)); ok {
+		return rf(ctx, authToken, dockerID)
+	}
 	if rf, ok := ret.Get(0).(func(context.Context, string, string) []*model.SubscriptionDetail); ok {
 		r0 = rf(ctx, authToken, dockerID)
 	} else {
@@ -136,7 +405,6 @@ func (_m *Client) ListSubscriptionsDetails(ctx context.Context, authToken string
 		}
 	}
 
-	var r1 error
 	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
 		r1 = rf(ctx, authToken, dockerID)
 	} else {
@@ -150,7 +418,15 @@ func (_m *Client) ListSubscriptionsDetails(ctx context.Context, authToken string
 func (_m *Client) LoadLocalLicense(ctx context.Context, dclnt licensing.WrappedDockerClient) (*model.Subscription, error) {
 	ret := _m.Called(ctx, dclnt)
 
+	if len(ret) == 0 {
+		panic("no return value specified for LoadLocalLicense")
+	}
+
 	var r0 *model.Subscription
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, licensing.WrappedDockerClient) (*model.Subscription, error)); ok {
+		return rf(ctx, dclnt)
+	}
 	if rf, ok := ret.Get(0).(func(context.Context, licensing.WrappedDockerClient) *model.Subscription); ok {
 		r0 = rf(ctx, dclnt)
 	} else {
@@ -159,7 +435,6 @@ func (_m *Client) LoadLocalLicense(ctx context.Context, dclnt licensing.WrappedD
 		}
 	}
 
-	var r1 error
 	if rf, ok := ret.Get(1).(func(context.Context, licensing.WrappedDockerClient) error); ok {
 		r1 = rf(ctx, dclnt)
 	} else {
@@ -169,18 +444,53 @@ func (_m *Client) LoadLocalLicense(ctx context.Context, dclnt licensing.WrappedD
 	return r0, r1
 }
 
+// Login provides a mock function with given fields: ctx, creds
+func (_m *Client) Login(ctx context.Context, creds licensing.Credentials) (string, error) {
+	ret := _m.Called(ctx, creds)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Login")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, licensing.Credentials) (string, error)); ok {
+		return rf(ctx, creds)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, licensing.Credentials) string); ok {
+		r0 = rf(ctx, creds)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, licensing.Credentials) error); ok {
+		r1 = rf(ctx, creds)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // LoginViaAuth provides a mock function with given fields: ctx, username, password
 func (_m *Client) LoginViaAuth(ctx context.Context, username string, password string) (string, error) {
 	ret := _m.Called(ctx, username, password)
 
+	if len(ret) == 0 {
+		panic("no return value specified for LoginViaAuth")
+	}
+
 	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (string, error)); ok {
+		return rf(ctx, username, password)
+	}
 	if rf, ok := ret.Get(0).(func(context.Context, string, string) string); ok {
 		r0 = rf(ctx, username, password)
 	} else {
 		r0 = ret.Get(0).(string)
 	}
 
-	var r1 error
 	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
 		r1 = rf(ctx, username, password)
 	} else {
@@ -190,11 +500,47 @@ func (_m *Client) LoginViaAuth(ctx context.Context, username string, password st
 	return r0, r1
 }
 
+// LoginWith2FA provides a mock function with given fields: ctx, login2FAToken, code
+func (_m *Client) LoginWith2FA(ctx context.Context, login2FAToken string, code string) (string, error) {
+	ret := _m.Called(ctx, login2FAToken, code)
+
+	if len(ret) == 0 {
+		panic("no return value specified for LoginWith2FA")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (string, error)); ok {
+		return rf(ctx, login2FAToken, code)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) string); ok {
+		r0 = rf(ctx, login2FAToken, code)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, login2FAToken, code)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // ParseLicense provides a mock function with given fields: license
 func (_m *Client) ParseLicense(license []byte) (*model.IssuedLicense, error) {
 	ret := _m.Called(license)
 
+	if len(ret) == 0 {
+		panic("no return value specified for ParseLicense")
+	}
+
 	var r0 *model.IssuedLicense
+	var r1 error
+	if rf, ok := ret.Get(0).(func([]byte) (*model.IssuedLicense, error)); ok {
+		return rf(license)
+	}
 	if rf, ok := ret.Get(0).(func([]byte) *model.IssuedLicense); ok {
 		r0 = rf(license)
 	} else {
@@ -203,7 +549,6 @@ func (_m *Client) ParseLicense(license []byte) (*model.IssuedLicense, error) {
 		}
 	}
 
-	var r1 error
 	if rf, ok := ret.Get(1).(func([]byte) error); ok {
 		r1 = rf(license)
 	} else {
@@ -213,10 +558,74 @@ func (_m *Client) ParseLicense(license []byte) (*model.IssuedLicense, error) {
 	return r0, r1
 }
 
+// QuerySubscriptions provides a mock function with given fields: ctx, authToken, query
+func (_m *Client) QuerySubscriptions(ctx context.Context, authToken string, query *model.SubscriptionQuery) ([]*model.SubscriptionDetail, error) {
+	ret := _m.Called(ctx, authToken, query)
+
+	if len(ret) == 0 {
+		panic("no return value specified for QuerySubscriptions")
+	}
+
+	var r0 []*model.SubscriptionDetail
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, *model.SubscriptionQuery) ([]*model.SubscriptionDetail, error)); ok {
+		return rf(ctx, authToken, query)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, *model.SubscriptionQuery) []*model.SubscriptionDetail); ok {
+		r0 = rf(ctx, authToken, query)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.SubscriptionDetail)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, *model.SubscriptionQuery) error); ok {
+		r1 = rf(ctx, authToken, query)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RenewSubscription provides a mock function with given fields: ctx, authToken, subscriptionID, request
+func (_m *Client) RenewSubscription(ctx context.Context, authToken string, subscriptionID string, request *model.SubscriptionRenewalRequest) (*model.SubscriptionDetail, error) {
+	ret := _m.Called(ctx, authToken, subscriptionID, request)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RenewSubscription")
+	}
+
+	var r0 *model.SubscriptionDetail
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, *model.SubscriptionRenewalRequest) (*model.SubscriptionDetail, error)); ok {
+		return rf(ctx, authToken, subscriptionID, request)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, *model.SubscriptionRenewalRequest) *model.SubscriptionDetail); ok {
+		r0 = rf(ctx, authToken, subscriptionID, request)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.SubscriptionDetail)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, *model.SubscriptionRenewalRequest) error); ok {
+		r1 = rf(ctx, authToken, subscriptionID, request)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // StoreLicense provides a mock function with given fields: ctx, dclnt, licenses, localRootDir
 func (_m *Client) StoreLicense(ctx context.Context, dclnt licensing.WrappedDockerClient, licenses *model.IssuedLicense, localRootDir string) error {
 	ret := _m.Called(ctx, dclnt, licenses, localRootDir)
 
+	if len(ret) == 0 {
+		panic("no return value specified for StoreLicense")
+	}
+
 	var r0 error
 	if rf, ok := ret.Get(0).(func(context.Context, licensing.WrappedDockerClient, *model.IssuedLicense, string) error); ok {
 		r0 = rf(ctx, dclnt, licenses, localRootDir)
@@ -227,13 +636,17 @@ func (_m *Client) StoreLicense(ctx context.Context, dclnt licensing.WrappedDocke
 	return r0
 }
 
-// SummarizeLicense provides a mock function with given fields: res, keyID
-func (_m *Client) SummarizeLicense(res *model.CheckResponse, keyID string) *model.Subscription {
-	ret := _m.Called(res, keyID)
+// SummarizeLicense provides a mock function with given fields: res
+func (_m *Client) SummarizeLicense(res *model.CheckResponse) *model.Subscription {
+	ret := _m.Called(res)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SummarizeLicense")
+	}
 
 	var r0 *model.Subscription
-	if rf, ok := ret.Get(0).(func(*model.CheckResponse, string) *model.Subscription); ok {
-		r0 = rf(res, keyID)
+	if rf, ok := ret.Get(0).(func(*model.CheckResponse) *model.Subscription); ok {
+		r0 = rf(res)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*model.Subscription)
@@ -243,11 +656,49 @@ func (_m *Client) SummarizeLicense(res *model.CheckResponse, keyID string) *mode
 	return r0
 }
 
+// UpdatePricingComponents provides a mock function with given fields: ctx, authToken, subscriptionID, request
+func (_m *Client) UpdatePricingComponents(ctx context.Context, authToken string, subscriptionID string, request *model.SubscriptionPricingComponentsUpdateRequest) (*model.SubscriptionDetail, error) {
+	ret := _m.Called(ctx, authToken, subscriptionID, request)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdatePricingComponents")
+	}
+
+	var r0 *model.SubscriptionDetail
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, *model.SubscriptionPricingComponentsUpdateRequest) (*model.SubscriptionDetail, error)); ok {
+		return rf(ctx, authToken, subscriptionID, request)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, *model.SubscriptionPricingComponentsUpdateRequest) *model.SubscriptionDetail); ok {
+		r0 = rf(ctx, authToken, subscriptionID, request)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.SubscriptionDetail)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, *model.SubscriptionPricingComponentsUpdateRequest) error); ok {
+		r1 = rf(ctx, authToken, subscriptionID, request)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // VerifyLicense provides a mock function with given fields: ctx, license
 func (_m *Client) VerifyLicense(ctx context.Context, license model.IssuedLicense) (*model.CheckResponse, error) {
 	ret := _m.Called(ctx, license)
 
+	if len(ret) == 0 {
+		panic("no return value specified for VerifyLicense")
+	}
+
 	var r0 *model.CheckResponse
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, model.IssuedLicense) (*model.CheckResponse, error)); ok {
+		return rf(ctx, license)
+	}
 	if rf, ok := ret.Get(0).(func(context.Context, model.IssuedLicense) *model.CheckResponse); ok {
 		r0 = rf(ctx, license)
 	} else {
@@ -256,7 +707,6 @@ func (_m *Client) VerifyLicense(ctx context.Context, license model.IssuedLicense
 		}
 	}
 
-	var r1 error
 	if rf, ok := ret.Get(1).(func(context.Context, model.IssuedLicense) error); ok {
 		r1 = rf(ctx, license)
 	} else {
@@ -265,3 +715,17 @@ func (_m *Client) VerifyLicense(ctx context.Context, license model.IssuedLicense
 
 	return r0, r1
 }
+
+// NewClient creates a new instance of Client. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewClient(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *Client {
+	mock := &Client{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
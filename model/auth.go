@@ -0,0 +1,66 @@
+package model
+
+import (
+	"fmt"
+
+	"github.com/docker/licensing/lib/errors"
+)
+
+// DeviceAuthorization holds the response of an OAuth2 device authorization endpoint (RFC 8628)
+type DeviceAuthorization struct {
+	DeviceCode string `json:"device_code"`
+	// UserCode must be entered by the user at VerificationURI
+	UserCode        string `json:"user_code"`
+	VerificationURI string `json:"verification_uri"`
+	// VerificationURIComplete, if set, includes the user code
+	VerificationURIComplete string `json:"verification_uri_complete,omitempty"`
+	// ExpiresIn is the lifetime in seconds of the device and user codes
+	ExpiresIn int `json:"expires_in"`
+	// Interval is the minimum number of seconds between token requests
+	Interval int `json:"interval,omitempty"`
+}
+
+// OAuthToken holds the response of an OAuth2 token endpoint
+type OAuthToken struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+// String redacts the tokens
+func (t OAuthToken) String() string {
+	return fmt.Sprintf("{TokenType:%s ExpiresIn:%d Scope:%s AccessToken:%s}", t.TokenType, t.ExpiresIn, t.Scope, errors.RedactedText)
+}
+
+// OAuth2 device flow error codes
+const (
+	OAuthAuthorizationPending = "authorization_pending"
+	OAuthSlowDown             = "slow_down"
+	OAuthAccessDenied         = "access_denied"
+	OAuthExpiredToken         = "expired_token"
+)
+
+// OAuthError wraps both the http error and the error returned by an OAuth2 endpoint
+type OAuthError struct {
+	*errors.HTTPError
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+var _ error = (*OAuthError)(nil)
+
+func (e *OAuthError) Error() string {
+	msg := e.HTTPError.Error()
+	if e.Code != "" {
+		msg = fmt.Sprintf("%s (%s", msg, e.Code)
+		if e.Description != "" {
+			msg = fmt.Sprintf("%s: %s", msg, e.Description)
+		}
+		msg += ")"
+	}
+
+	return msg
+}
@@ -0,0 +1,160 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// APIError is the structured body of an error response. It understands RFC 7807 problem details
+// (https://tools.ietf.org/html/rfc7807), as well as the `message`, `detail` and validation map formats
+// used by the billing and accounts services.
+type APIError struct {
+	// RFC 7807 members
+	Type     string `json:"type,omitempty"`
+	Title    string `json:"title,omitempty"`
+	Status   int    `json:"status,omitempty"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+
+	// Message is the legacy error message format
+	Message string `json:"message,omitempty"`
+
+	// FieldErrors holds validation errors keyed by field name, taken from RFC 7807 `invalid-params`,
+	// an `errors` member, or validation maps such as `{"username": ["This field is required."]}`
+	FieldErrors map[string][]string `json:"field_errors,omitempty"`
+
+	// Extensions holds any other members of the error body
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+var _ error = (*APIError)(nil)
+
+func (e *APIError) Error() string {
+	return e.Summary()
+}
+
+// Summary returns a human readable description of the error, including any field errors
+func (e *APIError) Summary() string {
+	var parts []string
+	for _, msg := range []string{e.Detail, e.Message, e.Title} {
+		if msg != "" {
+			parts = append(parts, msg)
+			break
+		}
+	}
+
+	fields := make([]string, 0, len(e.FieldErrors))
+	for field := range e.FieldErrors {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		parts = append(parts, fmt.Sprintf("%s: %s", field, strings.Join(e.FieldErrors[field], ", ")))
+	}
+
+	return strings.Join(parts, "; ")
+}
+
+// UnmarshalJSON decodes the known members, collecting field errors and extensions from the rest
+func (e *APIError) UnmarshalJSON(data []byte) error {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(data, &members); err != nil {
+		return err
+	}
+
+	known := map[string]interface{}{
+		"type":     &e.Type,
+		"title":    &e.Title,
+		"status":   &e.Status,
+		"detail":   &e.Detail,
+		"instance": &e.Instance,
+		"message":  &e.Message,
+	}
+
+	for name, raw := range members {
+		if dst, ok := known[name]; ok {
+			// tolerate unexpected member types rather than losing the whole error
+			if err := json.Unmarshal(raw, dst); err == nil {
+				continue
+			}
+		}
+
+		switch name {
+		case "invalid-params", "invalid_params", "errors":
+			if e.addFieldErrors(raw) {
+				continue
+			}
+		default:
+			var msgs []string
+			if _, isKnown := known[name]; !isKnown && json.Unmarshal(raw, &msgs) == nil {
+				e.addFieldError(name, msgs...)
+				continue
+			}
+		}
+
+		var ext interface{}
+		if err := json.Unmarshal(raw, &ext); err != nil {
+			return err
+		}
+		if e.Extensions == nil {
+			e.Extensions = make(map[string]interface{})
+		}
+		e.Extensions[name] = ext
+	}
+
+	return nil
+}
+
+// addFieldErrors decodes a list of `{"name": ..., "reason": ...}` params, or a map of field names
+// to one or more messages, returning false if raw is in neither format
+func (e *APIError) addFieldErrors(raw json.RawMessage) bool {
+	var params []struct {
+		Name    string `json:"name"`
+		Field   string `json:"field"`
+		Reason  string `json:"reason"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(raw, &params); err == nil {
+		for _, p := range params {
+			name, reason := p.Name, p.Reason
+			if name == "" {
+				name = p.Field
+			}
+			if reason == "" {
+				reason = p.Message
+			}
+			e.addFieldError(name, reason)
+		}
+		return true
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return false
+	}
+	decoded := make(map[string][]string, len(fields))
+	for name, value := range fields {
+		var msgs []string
+		var msg string
+		if err := json.Unmarshal(value, &msgs); err == nil {
+			decoded[name] = msgs
+		} else if err := json.Unmarshal(value, &msg); err == nil {
+			decoded[name] = []string{msg}
+		} else {
+			return false
+		}
+	}
+	for name, msgs := range decoded {
+		e.addFieldError(name, msgs...)
+	}
+	return true
+}
+
+func (e *APIError) addFieldError(name string, msgs ...string) {
+	if e.FieldErrors == nil {
+		e.FieldErrors = make(map[string][]string)
+	}
+	e.FieldErrors[name] = append(e.FieldErrors[name], msgs...)
+}
@@ -1,6 +1,11 @@
 package model
 
-import "time"
+import (
+	"fmt"
+	"time"
+
+	"github.com/docker/licensing/lib/errors"
+)
 
 // A CheckResponse is the internal content of the PublicCheckResponse signed
 // json blob.
@@ -35,6 +40,16 @@ type IssuedLicense struct {
 	Authorization string `json:"authorization"`
 }
 
+// String redacts the license private key
+func (l IssuedLicense) String() string {
+	return fmt.Sprintf("{KeyID:%s PrivateKey:%s Authorization:%s}", l.KeyID, errors.RedactedText, l.Authorization)
+}
+
+// GoString redacts the license private key
+func (l IssuedLicense) GoString() string {
+	return fmt.Sprintf("model.IssuedLicense{KeyID:%q, PrivateKey:%q, Authorization:%q}", l.KeyID, errors.RedactedText, l.Authorization)
+}
+
 // Valid returns true if the License is syntactically valid, false otherwise
 func (l *IssuedLicense) Valid() (bool, string) {
 	if l.KeyID == "" {
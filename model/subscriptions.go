@@ -2,9 +2,13 @@ package model
 
 import (
 	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/docker/licensing/lib/errors"
 	validation "github.com/docker/licensing/lib/go-validation"
 	"github.com/docker/licensing/types"
 )
@@ -117,6 +121,291 @@ type SubscriptionDetail struct {
 	MarketingOptIn bool `json:"marketing_opt_in"`
 }
 
+// TrialIneligibilityReason explains why a user may not start a free trial
+type TrialIneligibilityReason string
+
+const (
+	// TrialAlreadyUsed means the user has, or previously had, a free trial subscription
+	TrialAlreadyUsed TrialIneligibilityReason = "trial_already_used"
+	// TrialHasSubscription means the user already has a live docker enterprise subscription
+	TrialHasSubscription TrialIneligibilityReason = "has_subscription"
+)
+
+// TrialEligibility describes whether a user may start a free trial
+type TrialEligibility struct {
+	Eligible bool `json:"eligible"`
+
+	// Reason is set when the user is not eligible
+	Reason TrialIneligibilityReason `json:"reason,omitempty"`
+
+	// SubscriptionID identifies the subscription that makes the user ineligible, if any
+	SubscriptionID string `json:"subscription_id,omitempty"`
+}
+
+// SubscriptionSort names a field subscription listings can be sorted by
+type SubscriptionSort string
+
+// SubscriptionSort enumeration
+const (
+	SortByName    SubscriptionSort = "name"
+	SortByState   SubscriptionSort = "state"
+	SortByStart   SubscriptionSort = "current_period_start"
+	SortByExpires SubscriptionSort = "current_period_end"
+	SortByCreated SubscriptionSort = "initial_period_start"
+)
+
+// SubscriptionQuery describes a filtered, sorted and paginated subscription listing. Empty fields do not
+// filter. Filters on multiple values match any of the values.
+//
+// DockerID, PartnerAccountID, Origin, IncludeOrgs and the paging fields are sent to the billing service. The
+// other filters are applied by the client to the subscriptions it returns, see Matches, and so is the sort, see
+// SortSubscriptions. As the billing service pages the subscriptions before they are filtered and sorted, these
+// apply per page: a page may hold fewer than PageSize matching subscriptions, even none while later pages hold
+// some, and the sort does not order subscriptions across pages.
+type SubscriptionQuery struct {
+	DockerID         string
+	PartnerAccountID string
+	Origin           string
+
+	// If true, subscriptions of the orgs DockerID is a member of are included
+	IncludeOrgs bool
+
+	States          []types.State
+	ProductIDs      []string
+	ProductIDPrefix string
+	RatePlans       []string
+
+	// Bounds on the start and end of the subscriptions' current period
+	StartAfter    *time.Time
+	StartBefore   *time.Time
+	ExpiresAfter  *time.Time
+	ExpiresBefore *time.Time
+
+	Sort       SubscriptionSort
+	Descending bool
+
+	// Page is 1 based. If zero, the billing service defaults apply.
+	Page     int
+	PageSize int
+}
+
+// Values encodes the query parameters of the billing subscriptions endpoint. Filters the endpoint does not
+// support are left out, see SubscriptionQuery.
+func (q *SubscriptionQuery) Values() url.Values {
+	values := url.Values{}
+
+	setNonEmpty := func(key, value string) {
+		if value != "" {
+			values.Set(key, value)
+		}
+	}
+
+	setNonEmpty("docker_id", q.DockerID)
+	setNonEmpty("partner_account_id", q.PartnerAccountID)
+	setNonEmpty("origin", q.Origin)
+	if q.IncludeOrgs {
+		values.Set("include_orgs", "true")
+	}
+
+	if q.Page != 0 {
+		values.Set("page", strconv.Itoa(q.Page))
+	}
+	if q.PageSize != 0 {
+		values.Set("page_size", strconv.Itoa(q.PageSize))
+	}
+
+	return values
+}
+
+// Validate returns true if the subscription query is valid, false otherwise.
+// If invalid, one or more validation Errors will be returned.
+func (q *SubscriptionQuery) Validate() (bool, validation.Errors) {
+	var errs validation.Errors
+
+	for i, state := range q.States {
+		switch state {
+		case types.Active, types.Expired, types.Cancelled, types.Preparing, types.Failed:
+		default:
+			errs = append(errs, validation.InvalidValue(fmt.Sprintf("state[%v]", i), state))
+		}
+	}
+
+	if q.StartAfter != nil && q.StartBefore != nil && q.StartBefore.Before(*q.StartAfter) {
+		errs = append(errs, validation.InvalidValue("start_before", q.StartBefore.Format(time.RFC3339)))
+	}
+
+	if q.ExpiresAfter != nil && q.ExpiresBefore != nil && q.ExpiresBefore.Before(*q.ExpiresAfter) {
+		errs = append(errs, validation.InvalidValue("expires_before", q.ExpiresBefore.Format(time.RFC3339)))
+	}
+
+	switch q.Sort {
+	case "", SortByName, SortByState, SortByStart, SortByExpires, SortByCreated:
+	default:
+		errs = append(errs, validation.InvalidValue("sort", q.Sort))
+	}
+
+	if q.Page < 0 {
+		errs = append(errs, validation.InvalidValue("page", q.Page))
+	}
+
+	if q.PageSize < 0 {
+		errs = append(errs, validation.InvalidValue("page_size", q.PageSize))
+	}
+
+	valid := len(errs) == 0
+	return valid, errs
+}
+
+// Matches returns true if sub satisfies the query's state, product, rate plan and date filters. The filters sent
+// to the billing service, such as origin, are not checked.
+func (q *SubscriptionQuery) Matches(sub *Subscription) bool {
+	if len(q.States) > 0 && !containsString(statesToStrings(q.States), sub.State) {
+		return false
+	}
+
+	if len(q.ProductIDs) > 0 && !containsString(q.ProductIDs, sub.ProductID) {
+		return false
+	}
+
+	if q.ProductIDPrefix != "" && !strings.HasPrefix(sub.ProductID, q.ProductIDPrefix) {
+		return false
+	}
+
+	if len(q.RatePlans) > 0 && !containsString(q.RatePlans, sub.ProductRatePlan) {
+		return false
+	}
+
+	return inRange(sub.Start, q.StartAfter, q.StartBefore) && inRange(sub.Expires, q.ExpiresAfter, q.ExpiresBefore)
+}
+
+// SortSubscriptions sorts subs by the query's Sort, in place. Sorting is stable, and subscriptions missing the
+// sorted time come last.
+func (q *SubscriptionQuery) SortSubscriptions(subs []*SubscriptionDetail) {
+	if q.Sort == "" {
+		return
+	}
+
+	sort.SliceStable(subs, func(i, j int) bool {
+		a, b := subs[i], subs[j]
+		switch q.Sort {
+		case SortByName:
+			return stringLess(a.Name, b.Name, q.Descending)
+		case SortByState:
+			return stringLess(a.State, b.State, q.Descending)
+		case SortByStart:
+			return timeLess(a.Start, b.Start, q.Descending)
+		case SortByExpires:
+			return timeLess(a.Expires, b.Expires, q.Descending)
+		case SortByCreated:
+			return timeLess(&a.InitialPeriodStart, &b.InitialPeriodStart, q.Descending)
+		}
+		return false
+	})
+}
+
+// stringLess orders strings in ascending or descending order
+func stringLess(a, b string, descending bool) bool {
+	if descending {
+		return a > b
+	}
+	return a < b
+}
+
+// timeLess orders times in ascending or descending order, missing times last
+func timeLess(a, b *time.Time, descending bool) bool {
+	if a == nil || a.IsZero() {
+		return false
+	}
+	if b == nil || b.IsZero() {
+		return true
+	}
+	if descending {
+		return a.After(*b)
+	}
+	return a.Before(*b)
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func statesToStrings(states []types.State) []string {
+	strs := make([]string, len(states))
+	for i, state := range states {
+		strs[i] = string(state)
+	}
+	return strs
+}
+
+// inRange returns true if t is within the given bounds. Unbounded ranges always match; bounded ranges never
+// match a missing time.
+func inRange(t, after, before *time.Time) bool {
+	if after == nil && before == nil {
+		return true
+	}
+	if t == nil {
+		return false
+	}
+	if after != nil && t.Before(*after) {
+		return false
+	}
+	if before != nil && t.After(*before) {
+		return false
+	}
+	return true
+}
+
+// OwnerSubscriptions holds the subscriptions owned by a user or org
+type OwnerSubscriptions struct {
+	DockerID string `json:"docker_id"`
+
+	// Orgname is empty for the user's own account
+	Orgname string `json:"orgname,omitempty"`
+
+	Subscriptions []*SubscriptionDetail `json:"subscriptions"`
+
+	// Err is set if the subscriptions of this owner could not be listed
+	Err error `json:"-"`
+
+	// Error is the message of Err, so that serialized results keep the failure
+	Error string `json:"error,omitempty"`
+}
+
+// AccessibleSubscriptions holds the subscriptions a user can manage, grouped by owner. The user's own account
+// comes first, followed by their orgs.
+type AccessibleSubscriptions struct {
+	Owners []*OwnerSubscriptions `json:"owners"`
+}
+
+// Failed returns the owners whose subscriptions could not be listed, including those of deserialized results
+func (a *AccessibleSubscriptions) Failed() []*OwnerSubscriptions {
+	var failed []*OwnerSubscriptions
+	for _, owner := range a.Owners {
+		if owner.Err != nil || owner.Error != "" {
+			failed = append(failed, owner)
+		}
+	}
+	return failed
+}
+
+// Err returns the errors of all failed owners joined together, or nil if there were none
+func (a *AccessibleSubscriptions) Err() error {
+	var errs []error
+	for _, owner := range a.Failed() {
+		if owner.Err != nil {
+			errs = append(errs, owner.Err)
+		} else {
+			errs = append(errs, errors.New(owner.Error))
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // SubscriptionPricingComponent captures pricing component values that have been selected by the user.
 type SubscriptionPricingComponent struct {
 	Name  string `json:"name"`
@@ -181,15 +470,109 @@ func (s *SubscriptionCreationRequest) Validate() (bool, validation.Errors) {
 		errs = append(errs, validation.InvalidEmpty("product_rate_plan"))
 	}
 
-	for i, component := range s.PricingComponents {
+	if s.Start != nil && s.End != nil && !s.End.After(*s.Start) {
+		errs = append(errs, validation.InvalidValue("end", s.End.Format(time.RFC3339)))
+	}
+
+	for i, code := range s.CouponCodes {
+		if validation.IsEmpty(code) {
+			errs = append(errs, validation.InvalidEmpty(fmt.Sprintf("coupon_codes[%v]", i)))
+		}
+	}
+
+	errs = append(errs, validatePricingComponents(s.PricingComponents)...)
+
+	if !validation.IsEmpty(s.OrderItemID) && validation.IsEmpty(s.OrderID) {
+		errs = append(errs, validation.InvalidEmpty("order_id"))
+	}
+
+	if !validation.IsEmpty(s.PartnerAccountID) && validation.IsEmpty(s.ManagingPartnerID) {
+		errs = append(errs, validation.InvalidEmpty("managing_partner_id"))
+	}
+
+	valid := len(errs) == 0
+	return valid, errs
+}
+
+// SubscriptionCancellationRequest represents a subscription cancellation request
+type SubscriptionCancellationRequest struct {
+	// If true, the subscription is cancelled immediately rather than at the end of the current period
+	Immediate bool `json:"immediate,omitempty"`
+
+	// Optional free form reason for the cancellation
+	Reason string `json:"reason,omitempty"`
+}
+
+// Validate returns true if the cancellation request is valid, false otherwise.
+// If invalid, one or more validation Errors will be returned.
+func (s *SubscriptionCancellationRequest) Validate() (bool, validation.Errors) {
+	return true, nil
+}
+
+// SubscriptionPricingComponentsUpdateRequest represents a request to change the pricing component
+// values of a subscription
+type SubscriptionPricingComponentsUpdateRequest struct {
+	PricingComponents PricingComponents `json:"pricing_components"`
+}
+
+// Validate returns true if the pricing components update request is valid, false otherwise.
+// If invalid, one or more validation Errors will be returned.
+func (s *SubscriptionPricingComponentsUpdateRequest) Validate() (bool, validation.Errors) {
+	var errs validation.Errors
+
+	if validation.IsEmpty(s.PricingComponents) {
+		errs = append(errs, validation.InvalidEmpty("pricing_components"))
+	}
+
+	errs = append(errs, validatePricingComponents(s.PricingComponents)...)
+
+	valid := len(errs) == 0
+	return valid, errs
+}
+
+// SubscriptionRenewalRequest represents a subscription renewal request
+type SubscriptionRenewalRequest struct {
+	// Optional pricing component values for the renewed period. If empty, the current values are kept.
+	PricingComponents PricingComponents `json:"pricing_components,omitempty"`
+
+	CouponCodes []string `json:"coupon_codes,omitempty"`
+
+	Eusa *EusaState `json:"eusa,omitempty"`
+}
+
+// Validate returns true if the renewal request is valid, false otherwise.
+// If invalid, one or more validation Errors will be returned.
+func (s *SubscriptionRenewalRequest) Validate() (bool, validation.Errors) {
+	var errs validation.Errors
+
+	errs = append(errs, validatePricingComponents(s.PricingComponents)...)
+
+	for i, code := range s.CouponCodes {
+		if validation.IsEmpty(code) {
+			errs = append(errs, validation.InvalidEmpty(fmt.Sprintf("coupon_codes[%v]", i)))
+		}
+	}
+
+	valid := len(errs) == 0
+	return valid, errs
+}
+
+func validatePricingComponents(components PricingComponents) validation.Errors {
+	var errs validation.Errors
+
+	for i, component := range components {
 		if validation.IsEmpty(component.Name) {
 			name := fmt.Sprintf("pricing_component[%v]/name", i)
 			errs = append(errs, validation.InvalidEmpty(name))
 		}
+
+		if component.Value < 0 {
+			name := fmt.Sprintf("pricing_component[%v]/value", i)
+			errs = append(errs, validation.InvalidValue(name, component.Value))
+		}
 	}
 
-	valid := len(errs) == 0
-	return valid, errs
+	return errs
 }
 
 // EusaState encodes whether the subscription's EUSA has been accepted,
@@ -2,6 +2,8 @@ package model
 
 import (
 	"fmt"
+	"net/url"
+	"strconv"
 	"time"
 
 	"github.com/docker/licensing/lib/errors"
@@ -44,6 +46,21 @@ type PaginationParams struct {
 	Page     int
 }
 
+// Values encodes the pagination parameters as url query parameters, omitting zero values
+func (p PaginationParams) Values() url.Values {
+	values := url.Values{}
+
+	if p.PageSize != 0 {
+		values.Set("page_size", strconv.Itoa(p.PageSize))
+	}
+
+	if p.Page != 0 {
+		values.Set("page", strconv.Itoa(p.Page))
+	}
+
+	return values
+}
+
 // PaginatedMeta describes fields contained in a paginated response body
 type PaginatedMeta struct {
 	Count    int     `json:"count"`
@@ -64,6 +81,16 @@ type LoginRequest struct {
 	Password string `json:"password"`
 }
 
+// String redacts the password
+func (r LoginRequest) String() string {
+	return fmt.Sprintf("{Username:%s Password:%s}", r.Username, errors.RedactedText)
+}
+
+// GoString redacts the password
+func (r LoginRequest) GoString() string {
+	return fmt.Sprintf("model.LoginRequest{Username:%q, Password:%q}", r.Username, errors.RedactedText)
+}
+
 // LoginError wraps both the http error and raw hub login error
 type LoginError struct {
 	*errors.HTTPError
@@ -82,10 +109,43 @@ func (e *LoginError) Error() string {
 	return msg
 }
 
+// TwoFactorRequired returns true if the login was rejected because the account requires a second
+// authentication factor. The code must then be submitted along with Raw.Login2FAToken.
+func (e *LoginError) TwoFactorRequired() bool {
+	return e.Raw != nil && e.Raw.Login2FAToken != ""
+}
+
 // RawLoginError is the raw format of errors returned from the Accounts service.
 type RawLoginError struct {
 	Detail string `json:"detail,omitempty"`
 	// These fields wil be populated if it's a validation error
 	Username []string `json:"username,omitempty"`
 	Password []string `json:"password,omitempty"`
+	// Login2FAToken is populated if the account requires two factor authentication
+	Login2FAToken string `json:"login_2fa_token,omitempty"`
+}
+
+// String redacts the two factor login token
+func (e RawLoginError) String() string {
+	token := e.Login2FAToken
+	if token != "" {
+		token = errors.RedactedText
+	}
+	return fmt.Sprintf("{Detail:%s Username:%v Password:%v Login2FAToken:%s}", e.Detail, e.Username, e.Password, token)
+}
+
+// TwoFactorLoginRequest holds the second factor code submitted to complete a login
+type TwoFactorLoginRequest struct {
+	Login2FAToken string `json:"login_2fa_token"`
+	Code          string `json:"code"`
+}
+
+// String redacts the two factor login token and code
+func (r TwoFactorLoginRequest) String() string {
+	return fmt.Sprintf("{Login2FAToken:%s Code:%s}", errors.RedactedText, errors.RedactedText)
+}
+
+// GoString redacts the two factor login token and code
+func (r TwoFactorLoginRequest) GoString() string {
+	return fmt.Sprintf("model.TwoFactorLoginRequest{Login2FAToken:%q, Code:%q}", errors.RedactedText, errors.RedactedText)
 }
@@ -0,0 +1,129 @@
+package licensing
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/docker/licensing/lib/errors"
+	"github.com/docker/licensing/lib/go-clientlib"
+	"github.com/docker/licensing/model"
+)
+
+// hubPage is the body of a paginated hub response
+type hubPage[T any] struct {
+	model.PaginatedMeta
+	Results []T `json:"results"`
+}
+
+// Paginator iterates over the results of a paginated hub endpoint, fetching a page at a time and following
+// the Next link of each page until there is none.
+//
+//	p := client.IterateHubUserOrgs(ctx, authToken, 50)
+//	for p.Next() {
+//		org := p.Value()
+//	}
+//	if err := p.Err(); err != nil {
+//	}
+type Paginator[T any] struct {
+	ctx  context.Context
+	clnt *client
+
+	next    *url.URL
+	seen    map[string]bool
+	page    []T
+	current T
+	err     error
+}
+
+// OrgIterator iterates over the orgs of a hub user
+type OrgIterator = Paginator[model.Org]
+
+// newPaginator returns a Paginator starting at the given url
+func newPaginator[T any](ctx context.Context, c *client, first url.URL) *Paginator[T] {
+	return &Paginator[T]{
+		ctx:  ctx,
+		clnt: c,
+		next: &first,
+		seen: make(map[string]bool),
+	}
+}
+
+// Next advances to the next result, returning false once there are none left or an error occurred
+func (p *Paginator[T]) Next() bool {
+	for len(p.page) == 0 {
+		if p.err != nil || p.next == nil {
+			var zero T
+			p.current = zero
+			return false
+		}
+		p.fetch()
+	}
+
+	p.current = p.page[0]
+	p.page = p.page[1:]
+	return true
+}
+
+// Value returns the current result
+func (p *Paginator[T]) Value() T {
+	return p.current
+}
+
+// Err returns the error that stopped the iteration, if any
+func (p *Paginator[T]) Err() error {
+	return p.err
+}
+
+// All returns all remaining results
+func (p *Paginator[T]) All() ([]T, error) {
+	results := []T{}
+	for p.Next() {
+		results = append(results, p.Value())
+	}
+	return results, p.Err()
+}
+
+func (p *Paginator[T]) fetch() {
+	requrl := p.next
+	p.next = nil
+	p.seen[requrl.String()] = true
+
+	var response hubPage[T]
+	if _, _, err := p.clnt.doReq(p.ctx, "GET", requrl, clientlib.RecvJSON(&response)); err != nil {
+		p.err = err
+		return
+	}
+	p.page = response.Results
+
+	if response.Next == nil || *response.Next == "" {
+		return
+	}
+
+	next, err := p.clnt.nextPageURL(requrl, *response.Next)
+	if err != nil {
+		p.err = errors.Wrap(err, errors.Fields{
+			"next": *response.Next,
+		})
+		return
+	}
+	// guard against pages linking back to one already fetched
+	if !p.seen[next.String()] {
+		p.next = next
+	}
+}
+
+// nextPageURL resolves a Next link against the current page url. Only the path and query of the link are
+// used, so that credentials are never sent to a host other than the client's.
+func (c *client) nextPageURL(current *url.URL, link string) (*url.URL, error) {
+	ref, err := url.Parse(link)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := current.ResolveReference(ref)
+	next := c.baseURI
+	next.Path = resolved.Path
+	next.RawPath = resolved.RawPath
+	next.RawQuery = resolved.RawQuery
+	return &next, nil
+}
@@ -2,24 +2,58 @@ package licensing
 
 import (
 	"context"
-	"net/url"
+	"strings"
 
+	"github.com/docker/licensing/lib/errors"
 	"github.com/docker/licensing/lib/go-clientlib"
+	validation "github.com/docker/licensing/lib/go-validation"
 	"github.com/docker/licensing/model"
 )
 
 // RequestParams holds request parameters
+//
+// Deprecated: use model.SubscriptionQuery
 type RequestParams struct {
 	DockerID         string
 	PartnerAccountID string
 	Origin           string
 }
 
-func (c *client) createSubscription(ctx context.Context, request *model.SubscriptionCreationRequest) (*model.SubscriptionDetail, error) {
+// subscriptionID validates a subscription id path parameter
+type subscriptionIDParam string
+
+func (id subscriptionIDParam) Validate() (bool, validation.Errors) {
+	if validation.IsEmpty(string(id)) {
+		return false, validation.Errors{validation.InvalidEmpty("subscription_id")}
+	}
+	// the id is a single path segment, it must not address another resource
+	if strings.Contains(string(id), "/") || id == "." || id == ".." {
+		return false, validation.Errors{validation.InvalidValue("subscription_id", string(id))}
+	}
+	return true, nil
+}
+
+// validate runs each Validater, returning all of their validation Errors as an error if any is invalid
+func validate(validaters ...validation.Validater) error {
+	var errs validation.Errors
+	for _, v := range validaters {
+		if valid, verrs := v.Validate(); !valid {
+			errs = append(errs, verrs...)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (c *client) createSubscription(ctx context.Context, request *model.SubscriptionCreationRequest, opts ...clientlib.RequestOption) (*model.SubscriptionDetail, error) {
 	url := c.baseURI
 	url.Path += "/api/billing/v4/subscriptions"
 	response := new(model.SubscriptionDetail)
-	if _, _, err := c.doReq(ctx, "POST", &url, clientlib.SendJSON(request), clientlib.RecvJSON(response)); err != nil {
+	opts = append([]clientlib.RequestOption{clientlib.SendJSON(request), clientlib.RecvJSON(response)}, opts...)
+	if _, _, err := c.doReq(ctx, "POST", &url, opts...); err != nil {
 		return nil, err
 	}
 
@@ -30,23 +64,50 @@ func (c *client) getSubscription(ctx context.Context, id string) (*model.Subscri
 	url := c.baseURI
 	url.Path += "/api/billing/v4/subscriptions/" + id
 	response := new(model.SubscriptionDetail)
-	if _, _, err := c.doReq(ctx, "GET", &url, clientlib.RecvJSON(response)); err != nil {
+	if _, _, err := c.doReq(ctx, "GET", &url, clientlib.RecvJSON(response), notFoundErrorOpt(ErrSubscriptionNotFound)); err != nil {
 		return nil, err
 	}
 
 	return response, nil
 }
 
-func (c *client) listSubscriptions(ctx context.Context, params map[string]string) ([]*model.Subscription, error) {
-	values := url.Values{}
-	values.Set("docker_id", params["docker_id"])
-	values.Set("partner_account_id", params["partner_account_id"])
-	values.Set("origin", params["origin"])
-	values.Set("include_orgs", "true")
+func (c *client) cancelSubscription(ctx context.Context, id string, request *model.SubscriptionCancellationRequest) (*model.SubscriptionDetail, error) {
+	url := c.baseURI
+	url.Path += "/api/billing/v4/subscriptions/" + id + "/cancel"
+	response := new(model.SubscriptionDetail)
+	if _, _, err := c.doReq(ctx, "POST", &url, clientlib.SendJSON(request), clientlib.RecvJSON(response), notFoundErrorOpt(ErrSubscriptionNotFound)); err != nil {
+		return nil, err
+	}
 
+	return response, nil
+}
+
+func (c *client) updatePricingComponents(ctx context.Context, id string, request *model.SubscriptionPricingComponentsUpdateRequest) (*model.SubscriptionDetail, error) {
+	url := c.baseURI
+	url.Path += "/api/billing/v4/subscriptions/" + id + "/pricing-components"
+	response := new(model.SubscriptionDetail)
+	if _, _, err := c.doReq(ctx, "PUT", &url, clientlib.SendJSON(request), clientlib.RecvJSON(response), notFoundErrorOpt(ErrSubscriptionNotFound)); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+func (c *client) renewSubscription(ctx context.Context, id string, request *model.SubscriptionRenewalRequest) (*model.SubscriptionDetail, error) {
+	url := c.baseURI
+	url.Path += "/api/billing/v4/subscriptions/" + id + "/renew"
+	response := new(model.SubscriptionDetail)
+	if _, _, err := c.doReq(ctx, "POST", &url, clientlib.SendJSON(request), clientlib.RecvJSON(response), notFoundErrorOpt(ErrSubscriptionNotFound)); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+func (c *client) listSubscriptions(ctx context.Context, query *model.SubscriptionQuery) ([]*model.Subscription, error) {
 	url := c.baseURI
 	url.Path += "/api/billing/v4/subscriptions"
-	url.RawQuery = values.Encode()
+	url.RawQuery = query.Values().Encode()
 
 	response := make([]*model.Subscription, 0)
 	if _, _, err := c.doReq(ctx, "GET", &url, clientlib.RecvJSON(&response)); err != nil {
@@ -56,15 +117,10 @@ func (c *client) listSubscriptions(ctx context.Context, params map[string]string
 	return response, nil
 }
 
-func (c *client) listSubscriptionsDetails(ctx context.Context, params map[string]string) ([]*model.SubscriptionDetail, error) {
-	values := url.Values{}
-	values.Set("docker_id", params["docker_id"])
-	values.Set("partner_account_id", params["partner_account_id"])
-	values.Set("origin", params["origin"])
-
+func (c *client) listSubscriptionsDetails(ctx context.Context, query *model.SubscriptionQuery) ([]*model.SubscriptionDetail, error) {
 	url := c.baseURI
 	url.Path += "/api/billing/v4/subscriptions"
-	url.RawQuery = values.Encode()
+	url.RawQuery = query.Values().Encode()
 
 	response := make([]*model.SubscriptionDetail, 0)
 	if _, _, err := c.doReq(ctx, "GET", &url, clientlib.RecvJSON(&response)); err != nil {
@@ -73,3 +129,87 @@ func (c *client) listSubscriptionsDetails(ctx context.Context, params map[string
 
 	return response, nil
 }
+
+// defaultIteratorPageSize is the page size used by SubscriptionIterator if the query does not set one
+const defaultIteratorPageSize = 100
+
+// SubscriptionIterator iterates over the subscriptions matching a query, fetching a page at a time.
+//
+//	it := client.IterateSubscriptions(ctx, authToken, query)
+//	for it.Next() {
+//		sub := it.Subscription()
+//	}
+//	if err := it.Err(); err != nil {
+//	}
+type SubscriptionIterator struct {
+	ctx   context.Context
+	clnt  *client
+	query model.SubscriptionQuery
+
+	page    []*model.SubscriptionDetail
+	current *model.SubscriptionDetail
+	firstID string
+	done    bool
+	err     error
+}
+
+// Next advances to the next matching subscription, returning false once there are none left or an error occurred
+func (it *SubscriptionIterator) Next() bool {
+	for {
+		if it.err != nil {
+			return false
+		}
+
+		for len(it.page) > 0 {
+			sub := it.page[0]
+			it.page = it.page[1:]
+			if it.query.Matches(&sub.Subscription) {
+				it.current = sub
+				return true
+			}
+		}
+
+		if it.done {
+			it.current = nil
+			return false
+		}
+		it.fetch()
+	}
+}
+
+// Subscription returns the current subscription
+func (it *SubscriptionIterator) Subscription() *model.SubscriptionDetail {
+	return it.current
+}
+
+// Err returns the error that stopped the iteration, if any
+func (it *SubscriptionIterator) Err() error {
+	return it.err
+}
+
+func (it *SubscriptionIterator) fetch() {
+	subs, err := it.clnt.listSubscriptionsDetails(it.ctx, &it.query)
+	if err != nil {
+		it.err = errors.Wrap(err, errors.Fields{
+			"docker_id": it.query.DockerID,
+			"page":      it.query.Page,
+		})
+		return
+	}
+
+	if len(subs) > 0 {
+		// a repeated first page means the billing service ignored the pagination parameters
+		if subs[0].ID == it.firstID {
+			it.done = true
+			return
+		}
+		if it.firstID == "" {
+			it.firstID = subs[0].ID
+		}
+	}
+
+	// a short (or oversized, if pagination was ignored) page is the last one
+	it.done = len(subs) != it.query.PageSize
+	it.page = subs
+	it.query.Page++
+}
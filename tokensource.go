@@ -0,0 +1,150 @@
+package licensing
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/docker/licensing/lib/go-auth/jwt"
+)
+
+// defaultRefreshBefore is how long before their expiration cached tokens are refreshed by default
+const defaultRefreshBefore = time.Minute
+
+// TokenSource supplies the hub JWT of client methods called with an empty authToken, see Config.TokenSource
+type TokenSource interface {
+	// Token returns a valid token. clnt is the client requesting the token, through which it may log in.
+	Token(ctx context.Context, clnt Client) (string, error)
+	// Invalidate discards token after it was rejected by a service
+	Invalidate(token string)
+}
+
+// StaticToken is a TokenSource always returning the same token
+type StaticToken string
+
+// Token implements TokenSource
+func (t StaticToken) Token(ctx context.Context, clnt Client) (string, error) {
+	return string(t), nil
+}
+
+// Invalidate implements TokenSource. A static token cannot be replaced, so this is a no-op.
+func (t StaticToken) Invalidate(token string) {}
+
+// TokenSourceOptions holds CredentialsTokenSource options
+type TokenSourceOptions struct {
+	// If DecodeOptions holds a certificate chain or a key set, cached tokens are also checked with jwt.IsExpired
+	DecodeOptions jwt.DecodeOptions
+
+	// RefreshBefore is how long before its expiration a cached token is replaced, defaults to a minute
+	RefreshBefore time.Duration
+}
+
+// CredentialsTokenSource is a TokenSource logging in with Credentials, and caching the resulting token until it
+// is about to expire or is invalidated. Tokens without an expiration claim are cached until invalidated.
+// Concurrent calls needing a new token share a single login.
+type CredentialsTokenSource struct {
+	creds   Credentials
+	options TokenSourceOptions
+
+	mu    sync.Mutex
+	token string
+	login *login
+}
+
+// login is a login in progress, whose result is set once done is closed
+type login struct {
+	done  chan struct{}
+	token string
+	err   error
+}
+
+// NewCredentialsTokenSource returns a CredentialsTokenSource logging in with creds
+func NewCredentialsTokenSource(creds Credentials, options TokenSourceOptions) *CredentialsTokenSource {
+	if options.RefreshBefore == 0 {
+		options.RefreshBefore = defaultRefreshBefore
+	}
+
+	return &CredentialsTokenSource{
+		creds:   creds,
+		options: options,
+	}
+}
+
+// Token implements TokenSource. Callers waiting on a login started by another call get its result, including
+// its error.
+func (s *CredentialsTokenSource) Token(ctx context.Context, clnt Client) (string, error) {
+	// the token is checked without holding the lock, as verifying it may fetch a key set
+	s.mu.Lock()
+	token := s.token
+	s.mu.Unlock()
+
+	if token != "" && s.valid(token) {
+		return token, nil
+	}
+
+	s.mu.Lock()
+	l := s.login
+	if l == nil {
+		// another call may have replaced the token meanwhile
+		if s.token != "" && s.token != token {
+			token = s.token
+			s.mu.Unlock()
+			return token, nil
+		}
+
+		l = &login{done: make(chan struct{})}
+		s.login = l
+		s.mu.Unlock()
+
+		l.token, l.err = clnt.Login(ctx, s.creds)
+
+		s.mu.Lock()
+		s.login = nil
+		if l.err == nil {
+			s.token = l.token
+		}
+		s.mu.Unlock()
+		close(l.done)
+	} else {
+		s.mu.Unlock()
+	}
+
+	select {
+	case <-l.done:
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+
+	if l.err != nil {
+		return "", l.err
+	}
+	return l.token, nil
+}
+
+// Invalidate implements TokenSource
+func (s *CredentialsTokenSource) Invalidate(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token == token {
+		s.token = ""
+	}
+}
+
+// valid returns false if token has expired, or will within the refresh period
+func (s *CredentialsTokenSource) valid(token string) bool {
+	if s.options.DecodeOptions.CertificateChain != nil || s.options.DecodeOptions.KeySet != nil {
+		expired, err := jwt.IsExpired(token, s.options.DecodeOptions)
+		if err != nil || expired {
+			return false
+		}
+	}
+
+	expiresAt, err := jwt.ExpiresAt(token)
+	if err != nil || expiresAt.IsZero() {
+		// opaque tokens are used until rejected
+		return true
+	}
+
+	return time.Now().Add(s.options.RefreshBefore).Before(expiresAt)
+}
@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"net/url"
 
 	"github.com/docker/licensing/lib/errors"
 	"github.com/docker/licensing/lib/go-clientlib"
@@ -16,33 +15,17 @@ func (c *client) getUserByName(ctx context.Context, username string) (*model.Use
 	url := c.baseURI
 	url.Path += fmt.Sprintf("/v2/users/%s/", username)
 	response := new(model.User)
-	_, _, err := c.doRequestNoAuth(ctx, "GET", &url, clientlib.RecvJSON(response))
+	_, _, err := c.doRequestNoAuth(ctx, "GET", &url, clientlib.RecvJSON(response), notFoundErrorOpt(ErrUserNotFound))
 	return response, err
 }
 
-func (c *client) getUserOrgs(ctx context.Context, params model.PaginationParams) ([]model.Org, error) {
-	values := url.Values{}
-
-	if params.PageSize != 0 {
-		values.Set("page_size",
-			fmt.Sprintf("%v", params.PageSize))
-	}
-
-	if params.Page != 0 {
-		values.Set("page",
-			fmt.Sprintf("%v", params.Page))
-	}
-
+// userOrgs returns a paginator over the orgs of the authenticated user
+func (c *client) userOrgs(ctx context.Context, params model.PaginationParams) *OrgIterator {
 	requrl := c.baseURI
 	requrl.Path = "/v2/user/orgs/"
-	requrl.RawQuery = values.Encode()
+	requrl.RawQuery = params.Values().Encode()
 
-	var response struct {
-		model.PaginatedMeta
-		Results []model.Org `json:"results"`
-	}
-	_, _, err := c.doReq(ctx, "GET", &requrl, clientlib.RecvJSON(&response))
-	return response.Results, err
+	return newPaginator[model.Org](ctx, c, requrl)
 }
 
 // login calls the login endpoint
@@ -60,11 +43,25 @@ func (c *client) login(ctx context.Context, username string, password string) (*
 	return response, err
 }
 
+// login2FA calls the two factor login endpoint
+// If an error is returned by the Accounts service, `error` will be of type `LoginError`
+func (c *client) login2FA(ctx context.Context, login2FAToken, code string) (*model.LoginResult, error) {
+	url := c.baseURI
+	url.Path += "/v2/users/2fa-login/"
+	request := model.TwoFactorLoginRequest{
+		Login2FAToken: login2FAToken,
+		Code:          code,
+	}
+	response := new(model.LoginResult)
+	_, _, err := c.doRequestNoAuth(ctx, "POST", &url, clientlib.SendJSON(request), clientlib.RecvJSON(response), loginErrorCheckOpt)
+	return response, err
+}
+
 // loginErrorCheckOpt works similarly to `clientlib.DefaultErrorCheck`, except it parses the error response
 func loginErrorCheckOpt(r *clientlib.Request) {
 	r.ErrorCheck = func(r *clientlib.Request, doErr error, res *http.Response) error {
 		if doErr != nil {
-			return errors.Wrap(doErr, r.ErrorFields())
+			return transportError(r, doErr)
 		}
 		status := res.StatusCode
 		if status >= 200 && status < 300 {